@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/EmilioCliff/ignite/pkg/ignite"
+	"github.com/spf13/cobra"
+)
+
+func newAddCmd() *cobra.Command {
+	var (
+		path         string
+		setVariables []string
+	)
+
+	var usages []string
+
+	for _, c := range ignite.Components() {
+		if slices.Contains(usages, c.Option()) {
+			continue
+		}
+
+		usages = append(usages, c.Option())
+	}
+
+	for i, option := range usages {
+		usage := option
+		if choices := ignite.Choices(option); len(choices) > 0 {
+			usage += " <" + strings.Join(choices, "|") + ">"
+		}
+
+		usages[i] = "  ignite add " + usage
+	}
+
+	cmd := &cobra.Command{
+		Use:   "add <component> [type]",
+		Short: "Add a component to an existing ignite project",
+		Long: `add bolts a component onto a project previously generated by ignite.
+
+The files the component contributes are created, and its Makefile targets and
+README section are inserted into the existing files without touching the rest
+of their content. The project's .ignite.yaml is updated accordingly.
+
+Components:
+
+` + strings.Join(usages, "\n") + `
+
+Components provided by plugins (see ignite plugins list) are added by name. The
+answers to their questions are given with --set <component>.<question>=<value>,
+asked for when the standard input is a terminal, and default otherwise.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			given, err := parseSetFlags(setVariables)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if path == "" {
+				path, err = MustGetPwd()
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+
+			opts := ignite.AddOptions{
+				Component: args[0],
+				Variables: given,
+				Templates: templatesFromFlags(cmd),
+				Conflicts: conflictStrategyFromFlags(cmd),
+				Output:    os.Stdout,
+			}
+
+			if len(args) > 1 {
+				opts.Type = args[1]
+			}
+
+			if stdinIsTerminal() {
+				opts.Prompt = promptVariable
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			result, err := ignite.Add(ctx, path, opts)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Added %s to %s:\n", strings.ToLower(args[0]), result.Name)
+
+			for _, f := range result.Files {
+				if !f.Metadata {
+					fmt.Println("  " + f.Path)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&path, "path", "p", "", "Path of the project (defaults to current directory)")
+	cmd.Flags().StringArrayVar(&setVariables, "set", nil, "Answer a question of a plugin component (component.question=value, repeatable)")
+	addConflictFlags(cmd)
+
+	return cmd
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/EmilioCliff/ignite/pkg/ignite"
+	"github.com/spf13/cobra"
+)
+
+func newCheckCmd() *cobra.Command {
+	var (
+		jsonOutput     bool
+		dbType         string
+		controlType    string
+		withWorkflow   bool
+		withDockerfile bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check [project_path...]",
+		Short: "Report how projects deviate from their ignite blueprint",
+		Long: `check compares each project directory (defaults to the current directory)
+against what ignite would generate for it, and reports missing directories,
+deleted files and modified template files.
+
+The options are read from the project's .ignite.yaml. Flags override them and,
+for projects without one, describe the options they were generated with. A
+template file is modified when its checksum differs from the one recorded in
+.ignite.yaml, or from a fresh render of the templates if none is recorded.
+
+The command exits non-zero if any project has drifted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			if len(args) == 0 {
+				dir, err := MustGetPwd()
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+
+				args = []string{dir}
+			}
+
+			overrides, err := variablesFromFlags(cmd, nil)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			opts := ignite.CheckOptions{
+				Templates: templatesFromFlags(cmd),
+				Variables: overrides,
+			}
+
+			reports := make([]*ignite.DriftReport, 0, len(args))
+
+			for _, dir := range args {
+				report, err := ignite.Check(dir, opts)
+				if err != nil {
+					fmt.Printf("Error: %s: %v\n", dir, err)
+					os.Exit(1)
+				}
+
+				reports = append(reports, report)
+			}
+
+			if jsonOutput {
+				err = printDriftJSON(os.Stdout, reports)
+			} else {
+				printDriftText(os.Stdout, reports)
+			}
+
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			for _, report := range reports {
+				if !report.Clean {
+					os.Exit(1)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the reports as JSON")
+	cmd.Flags().StringVarP(&dbType, "database", "d", "", "Database type (one of: "+strings.Join(ignite.Choices("database"), ", ")+")")
+	cmd.Flags().StringVarP(&controlType, "controller", "c", "", "Controller type (one of: "+strings.Join(ignite.Choices("controller"), ", ")+")")
+	cmd.Flags().BoolVar(&withWorkflow, "withWorkflow", false, "Expect a GitHub Actions workflow")
+	cmd.Flags().BoolVar(&withDockerfile, "withDockerfile", false, "Expect a Dockerfile")
+
+	return cmd
+}
+
+func printDriftJSON(w io.Writer, reports []*ignite.DriftReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(reports)
+}
+
+func printDriftText(w io.Writer, reports []*ignite.DriftReport) {
+	for _, report := range reports {
+		if report.Clean {
+			fmt.Fprintf(w, "%s (%s): matches its blueprint\n", report.Project, report.Path)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%s (%s): %d issue(s)\n", report.Project, report.Path, len(report.Issues))
+
+		for _, issue := range report.Issues {
+			fmt.Fprintf(w, "  %-18s %s\n", strings.ReplaceAll(issue.Kind, "_", " "), issue.Path)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/EmilioCliff/ignite/pkg/ignite"
+	"github.com/spf13/cobra"
+)
+
+func newComponentsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "components",
+		Short: "List the components a project can be generated with",
+		Long: `components lists the built-in components, with the option selecting each
+one, and the components provided by plugins, added with --with.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			plugins, _ := ignite.Plugins()
+			printComponents(os.Stdout, ignite.Components(), plugins)
+		},
+	}
+}
+
+func printComponents(w io.Writer, components []ignite.Component, plugins []*ignite.PluginComponent) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tSELECTED WITH\tDESCRIPTION")
+
+	for _, c := range components {
+		flag := "--set " + c.Option() + "=" + c.Name()
+		if c.Name() == c.Option() {
+			// a toggle is selected by its own yes/no variable
+			flag = "--set " + c.Option() + "=yes"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name(), flag, c.Description())
+	}
+
+	for _, c := range plugins {
+		fmt.Fprintf(tw, "%s\t--with %s\t%s (plugin %s)\n", c.Name, c.Name, c.Description, filepath.Base(c.Plugin))
+	}
+
+	tw.Flush()
+}
+
+func newPluginsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "Inspect the plugins that provide extra components",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the installed plugins and their components",
+		Long: `list shows every component provided by a plugin and its questions.
+
+Plugins are executables called ignite-<name>, looked up in the ignite/plugins
+directory of the user's configuration directory and then in PATH. ignite asks
+each one to describe its components, writing a JSON request to its standard
+input and reading a JSON response from its standard output. Plugins that fail
+to answer are listed with the error.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			components, failures := ignite.Plugins()
+			printPlugins(os.Stdout, components, failures)
+		},
+	})
+
+	return cmd
+}
+
+func printPlugins(w io.Writer, components []*ignite.PluginComponent, failures map[string]error) {
+	if len(components) == 0 && len(failures) == 0 {
+		fmt.Fprintln(w, "No plugins installed")
+
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPONENT\tQUESTIONS\tPLUGIN\tDESCRIPTION")
+
+	for _, c := range components {
+		questions := make([]string, 0, len(c.Questions))
+		for _, q := range c.Questions {
+			questions = append(questions, q.Name)
+		}
+
+		if len(questions) == 0 {
+			questions = append(questions, "-")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, strings.Join(questions, ","), c.Plugin, c.Description)
+	}
+
+	tw.Flush()
+
+	failed := make([]string, 0, len(failures))
+	for executable := range failures {
+		failed = append(failed, executable)
+	}
+
+	sort.Strings(failed)
+
+	for _, executable := range failed {
+		fmt.Fprintf(w, "\nplugin %s failed: %v\n", executable, failures[executable])
+	}
+}
@@ -1,19 +1,24 @@
 package main
 
 import (
-	"embed"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 
+	"github.com/EmilioCliff/ignite/pkg/ignite"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-//go:embed templates/*.txt
-var templatesFS embed.FS
-
 func main() {
 	var (
 		dbType         string
@@ -23,11 +28,18 @@ func main() {
 		path           string
 		interactive    bool
 		verbose        bool
+		dryRun         bool
+		showContent    bool
+		inPlace        bool
+		archive        string
+		setVariables   []string
+		withComponents []string
 	)
 
 	var rootCmd = &cobra.Command{
-		Use:   "ignite <project_name>",
-		Short: "Initialize a new project with the specified name",
+		Use:     "ignite <project_name>",
+		Version: ignite.Version(),
+		Short:   "Initialize a new project with the specified name",
 		Long: `ignite initializes a new project.
 
 Usage examples:
@@ -35,83 +47,362 @@ Usage examples:
   ignite my_project
   ignite my_project --interactive 
   ignite my_project -d postgres -c http -p ./path/to/project
+  ignite github.com/acme/my_project -d mysql -c http --in-place
+  ignite my_project -d postgres -c grpc --dry-run --show-content
+  ignite my_project -d postgres -c http --archive my_project.tar.gz
+  ignite my_project -d postgres -c http --set workflow=yes
+  ignite my_project -d postgres -c http --with authclient --set authclient.provider=oidc
 
-Supported Database Types: postgres, mysql, sqlite, mongodb
-Supported Controllers: user, auth, product, order`,
+Supported Database Types: ` + strings.Join(ignite.Choices("database"), ", ") + `
+Supported Controllers: ` + strings.Join(ignite.Choices("controller"), ", "),
 
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
 				return fmt.Errorf("missing project name: the first argument must be the project name")
 			}
 
+			if err := ignite.ValidateProjectName(args[0]); err != nil {
+				return err
+			}
+
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			logFile, err := os.OpenFile(".logs", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			var err error
+
+			if dryRun {
+				// a dry run must not touch the disk, so logs never go to the .logs file
+				setupConsoleLogging(verbose)
+			} else {
+				logFile, err := os.OpenFile(".logs", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					fmt.Printf("Error opening log file: %v\n", err)
+					os.Exit(1)
+				}
+				defer logFile.Close()
+
+				var logOutput io.Writer = logFile
+				if verbose {
+					logOutput = io.MultiWriter(os.Stdout, logFile)
+				}
+				log.SetOutput(logOutput)
+			}
+
+			given, err := variablesFromFlags(cmd, setVariables)
 			if err != nil {
-				fmt.Printf("Error opening log file: %v\n", err)
+				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
-			defer logFile.Close()
 
-			var logOutput io.Writer = logFile
-			if verbose {
-				logOutput = io.MultiWriter(os.Stdout, logFile)
+			opts := ignite.Options{
+				Module:    args[0],
+				Variables: given,
+				With:      withComponents,
+				Templates: templatesFromFlags(cmd),
+				InPlace:   inPlace,
+				Conflicts: conflictStrategyFromFlags(cmd),
+				Hooks:     hookPolicyFromFlags(cmd),
+				Output:    os.Stdout,
+			}
+
+			// check if it will run in interactive or manual way
+			if interactive || len(args) == 1 && given["database"] == "" {
+				opts.Prompt = promptVariable
 			}
-			log.SetOutput(logOutput)
 
-			p := NewProjectInitializer(
-				path,
-				strings.ToLower(dbType),
-				strings.ToLower(controlType),
-				withWorkflow,
-				withDockerfile,
-				verbose,
-			)
+			if stdinIsTerminal() {
+				opts.ApproveHooks = approveHooks
+			}
 
-			p.projectName = args[0]
+			if dryRun {
+				result, err := ignite.Plan(opts)
+				if err == nil {
+					err = result.WriteTree(os.Stdout, showContent)
+				}
 
-			// check if it will run in interactive or manual way
-			if interactive || len(args) == 1 && dbType == "" {
-				runInInteractiveMode(p)
-			} else {
-				runFlagMode(p)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+
+				return
+			}
+
+			// generation is rolled back if the user interrupts it
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if archive != "" {
+				if err := generateArchive(ctx, opts, archive); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+
+				fmt.Println("Project written to", archive)
+
+				return
 			}
 
 			if path == "" {
-				path, err = os.Getwd()
+				path, err = MustGetPwd()
 				if err != nil {
 					log.Panic(err)
 				}
 			}
 
-			err = changeWorkingDir(path)
+			path, err = filepath.Abs(path)
 			if err != nil {
 				log.Panic(err)
 			}
 
-			projectName := args[0]
-			log.Println("Initializing project", projectName)
-
-			if err := p.runSetup(); err != nil {
+			if _, err := ignite.Generate(ctx, opts, ignite.DirFS(path)); err != nil {
 				fmt.Printf("Error: %v\n", err)
 				os.Exit(1)
 			}
+
+			fmt.Println("Project initialized successfully!")
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&path, "path", "p", "", "Path to create project (defaults to current directory)")
-	rootCmd.Flags().StringVarP(&dbType, "database", "d", "", "Database type (one of: postgres, mysql)")
-	rootCmd.Flags().StringVarP(&controlType, "controller", "c", "", "Controller type (one of: grpc, http)")
+	rootCmd.Flags().StringVarP(&path, "path", "p", "", "Directory in which the project directory is created (defaults to current directory)")
+	rootCmd.Flags().StringVarP(&dbType, "database", "d", "", "Database type (one of: "+strings.Join(ignite.Choices("database"), ", ")+")")
+	rootCmd.Flags().StringVarP(&controlType, "controller", "c", "", "Controller type (one of: "+strings.Join(ignite.Choices("controller"), ", ")+")")
 	rootCmd.Flags().BoolVar(&withWorkflow, "withWorkflow", false, "Include GitHub Actions workflow? (yes/no)")
 	rootCmd.Flags().BoolVar(&withDockerfile, "withDockerfile", false, "Include Dockerfile? (yes/no)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().String("templates", "", "Template directory or pack (path@ref, git+file:///repo@ref, module@version) overriding the user and embedded templates")
+	rootCmd.SetGlobalNormalizationFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		// --template is accepted as an alias of --templates
+		if name == "template" {
+			name = "templates"
+		}
+
+		return pflag.NormalizedName(name)
+	})
 	rootCmd.Flags().BoolVar(&interactive, "interactive", false, "Interactive mode")
+	rootCmd.Flags().StringArrayVar(&setVariables, "set", nil, "Set a template variable (name=value) or a plugin component answer (component.question=value), repeatable")
+	rootCmd.Flags().StringArrayVar(&withComponents, "with", nil, "Add a component provided by a plugin (repeatable, see ignite plugins list)")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the project that would be generated without writing anything")
+	rootCmd.Flags().BoolVar(&showContent, "show-content", false, "With --dry-run, also print the rendered contents of every file")
+
+	rootCmd.Flags().BoolVar(&inPlace, "in-place", false, "Generate directly into --path instead of a new <project_name> directory")
+	rootCmd.Flags().StringVar(&archive, "archive", "", "Write the project into a .tar, .tar.gz, .tgz or .zip archive instead of a directory")
+	rootCmd.MarkFlagsMutuallyExclusive("archive", "path")
+	rootCmd.MarkFlagsMutuallyExclusive("archive", "dry-run")
+	addConflictFlags(rootCmd)
+	addHookFlags(rootCmd)
 
 	rootCmd.MarkFlagsRequiredTogether("database", "controller")
 
+	rootCmd.AddCommand(newAddCmd())
+	rootCmd.AddCommand(newUpgradeCmd())
+	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newTemplatesCmd())
+	rootCmd.AddCommand(newComponentsCmd())
+	rootCmd.AddCommand(newPluginsCmd())
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// variablesFromFlags returns the template variables given on the command line:
+// the built-in -d, -c, --withWorkflow and --withDockerfile flags, if set, and
+// every --set name=value pair.
+func variablesFromFlags(cmd *cobra.Command, pairs []string) (map[string]string, error) {
+	given, err := parseSetFlags(pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	builtin := map[string]string{
+		"database":       "database",
+		"controller":     "controller",
+		"withWorkflow":   "workflow",
+		"withDockerfile": "dockerfile",
+	}
+
+	for flag, name := range builtin {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+
+		if _, ok := given[name]; ok {
+			return nil, fmt.Errorf("variable %s is given with both --%s and --set", name, flag)
+		}
+
+		value, _ := cmd.Flags().GetString(flag)
+		if b, err := cmd.Flags().GetBool(flag); err == nil {
+			value = strconv.FormatBool(b)
+		}
+
+		given[name] = strings.ToLower(value)
+	}
+
+	return given, nil
+}
+
+// generateArchive generates the project of opts into the archive file name,
+// which is removed again if generation fails.
+func generateArchive(ctx context.Context, opts ignite.Options, name string) error {
+	fsys, finish, err := createArchive(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = ignite.Generate(ctx, opts, fsys)
+	if finishErr := finish(); err == nil {
+		err = finishErr
+	}
+
+	if err != nil {
+		os.Remove(name)
+	}
+
+	return err
+}
+
+// createArchive creates the archive file name, in the format its extension
+// selects, and returns the file system writing into it and the function
+// completing and closing it.
+func createArchive(name string) (ignite.FS, func() error, error) {
+	var (
+		newFS    func(io.Writer) *ignite.ArchiveFS
+		compress bool
+	)
+
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		newFS = ignite.NewZipFS
+	case strings.HasSuffix(name, ".tar"):
+		newFS = ignite.NewTarFS
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		newFS, compress = ignite.NewTarFS, true
+	default:
+		return nil, nil, fmt.Errorf("unsupported archive %s: must end in .tar, .tar.gz, .tgz or .zip", name)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	var (
+		w  io.Writer = f
+		gz *gzip.Writer
+	)
+
+	if compress {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	fsys := newFS(w)
+
+	return fsys, func() error {
+		err := fsys.Close()
+		if gz != nil {
+			err = errors.Join(err, gz.Close())
+		}
+
+		return errors.Join(err, f.Close())
+	}, nil
+}
+
+// parseSetFlags parses --set name=value pairs.
+func parseSetFlags(pairs []string) (map[string]string, error) {
+	given := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --set %q: must be name=value", pair)
+		}
+
+		given[strings.TrimSpace(name)] = value
+	}
+
+	return given, nil
+}
+
+// templatesFromFlags returns the value of the --templates flag of cmd.
+func templatesFromFlags(cmd *cobra.Command) string {
+	templates, _ := cmd.Flags().GetString("templates")
+
+	return templates
+}
+
+// addConflictFlags registers the mutually exclusive --force, --skip-existing
+// and --merge flags on cmd.
+func addConflictFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(ignite.ConflictForce, false, "Overwrite files that already exist")
+	cmd.Flags().Bool(ignite.ConflictSkipExisting, false, "Keep files that already exist and only create the missing ones")
+	cmd.Flags().Bool(ignite.ConflictMerge, false, "Keep files that already exist and write the new version next to them as <file>.ignite-new")
+
+	cmd.MarkFlagsMutuallyExclusive(ignite.ConflictForce, ignite.ConflictSkipExisting, ignite.ConflictMerge)
+}
+
+// conflictStrategyFromFlags returns the strategy selected with the flags
+// registered by addConflictFlags.
+func conflictStrategyFromFlags(cmd *cobra.Command) string {
+	for _, strategy := range []string{ignite.ConflictForce, ignite.ConflictSkipExisting, ignite.ConflictMerge} {
+		if set, _ := cmd.Flags().GetBool(strategy); set {
+			return strategy
+		}
+	}
+
+	return ignite.ConflictAbort
+}
+
+// addHookFlags registers the mutually exclusive --trust-hooks and --no-hooks
+// flags on cmd.
+func addHookFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool(ignite.HooksTrust, false, "Run the hooks of template packs without asking for approval")
+	cmd.Flags().Bool(ignite.HooksSkip, false, "Do not run the hooks of template packs")
+
+	cmd.MarkFlagsMutuallyExclusive(ignite.HooksTrust, ignite.HooksSkip)
+}
+
+// hookPolicyFromFlags returns the policy selected with the flags registered by
+// addHookFlags.
+func hookPolicyFromFlags(cmd *cobra.Command) string {
+	for _, policy := range []string{ignite.HooksTrust, ignite.HooksSkip} {
+		if set, _ := cmd.Flags().GetBool(policy); set {
+			return policy
+		}
+	}
+
+	return ignite.HooksAsk
+}
+
+// setupConsoleLogging sends log output to stderr if verbose is set and discards
+// it otherwise. It is used by commands that must not write the .logs file.
+func setupConsoleLogging(verbose bool) {
+	log.SetOutput(io.Discard)
+	if verbose {
+		log.SetOutput(os.Stderr)
+	}
+}
+
+// stdinIsTerminal reports whether the standard input is a character device
+// other than the null device, so the user can be asked questions.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	null, err := os.Stat(os.DevNull)
+
+	return err != nil || !os.SameFile(info, null)
+}
+
+func MustGetPwd() (string, error) {
+	path, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	return path, nil
+}
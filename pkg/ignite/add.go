@@ -0,0 +1,349 @@
+package ignite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// addableComponent is a component that `ignite add` can bolt onto an existing
+// project. Adding a component only changes the project's options; the files it
+// contributes are derived by planning the project with and without it.
+type addableComponent struct {
+	name  string
+	usage string
+	// values lists the accepted values for components that take one (e.g. the
+	// database type). It is empty for components that take no value.
+	values []string
+	apply  func(p *projectInitializer, value string) error
+}
+
+// addableComponents are the components `ignite add` takes, one per project
+// option: the option's name, followed by the component to select for options
+// shared by several components.
+var addableComponents = optionComponents()
+
+func optionComponents() []addableComponent {
+	var addable []addableComponent
+
+	for _, option := range registry.options() {
+		choices := registry.choices(option)
+
+		if len(choices) == 0 {
+			addable = append(addable, addableComponent{
+				name:  option,
+				usage: option,
+				apply: func(p *projectInitializer, _ string) error {
+					if p.manifestOptionValues()[option] != "" {
+						return errComponentPresent
+					}
+
+					p.applyVariables(map[string]any{option: true})
+
+					return nil
+				},
+			})
+
+			continue
+		}
+
+		addable = append(addable, addableComponent{
+			name:   option,
+			usage:  option + " <" + strings.Join(choices, "|") + ">",
+			values: choices,
+			apply: func(p *projectInitializer, value string) error {
+				if current := p.manifestOptionValues()[option]; current != "" {
+					return fmt.Errorf("project already uses the %s %s", current, option)
+				}
+
+				p.applyVariables(map[string]any{option: value})
+
+				return nil
+			},
+		})
+	}
+
+	return addable
+}
+
+var errComponentPresent = errors.New("component is already part of the project")
+
+// AddOptions configures Add.
+type AddOptions struct {
+	// Component is the component to add: one of the project options
+	// (database, controller, workflow, dockerfile) or a component provided by
+	// a plugin.
+	Component string
+	// Type is the component to select for options shared by several
+	// components, e.g. "postgres" for the database.
+	Type string
+	// Variables holds the answers to the questions of a plugin component,
+	// named <component>.<question>.
+	Variables map[string]string
+	// Templates, if not empty, replaces the template pack recorded in the
+	// project's lock file (see Options.Templates).
+	Templates string
+	// Conflicts is the strategy for the new files that already exist, one of
+	// the Conflict constants.
+	Conflicts string
+	// Prompt, if not nil, asks for the answers not given in Variables, which
+	// otherwise get their default.
+	Prompt PromptFunc
+	// Output receives progress messages and the output of go get. Nil
+	// discards them.
+	Output io.Writer
+}
+
+// Add adds a component to the ignite project in dir and returns the files it
+// wrote: those the component contributes, the Makefile and README it inserted
+// its sections into, and the updated metadata.
+func Add(ctx context.Context, dir string, opts AddOptions) (*Result, error) {
+	args := []string{opts.Component}
+	if opts.Type != "" {
+		args = append(args, opts.Type)
+	}
+
+	component, value, err := lookupComponent(args, opts.Variables, opts.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := projectTemplateSet(opts.Templates, dir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return runAdd(ctx, dir, ts, component, value, opts.Conflicts, outputOf(opts.Output))
+}
+
+// lookupComponent resolves the component named by args[0], a built-in one or
+// one provided by a plugin, and validates the value in args[1], if the
+// component takes one. given holds the --set answers for plugin components,
+// and ask, if not nil, asks for the others.
+func lookupComponent(args []string, given map[string]string, ask PromptFunc) (addableComponent, string, error) {
+	name := strings.ToLower(args[0])
+
+	variables, answers := splitComponentAnswers(given)
+	if len(variables) > 0 || len(answers) > 1 || len(answers) == 1 && answers[name] == nil {
+		return addableComponent{}, "", fmt.Errorf("--set only answers the questions of the plugin component added, as %s.<question>=<value>", name)
+	}
+
+	for _, c := range addableComponents {
+		if c.name != name {
+			continue
+		}
+
+		if len(c.values) == 0 {
+			if len(args) > 1 {
+				return c, "", fmt.Errorf("component %s does not take a type", c.name)
+			}
+
+			return c, "", nil
+		}
+
+		if len(args) < 2 {
+			return c, "", fmt.Errorf("missing %s type: usage is ignite add %s", c.name, c.usage)
+		}
+
+		value := strings.ToLower(args[1])
+		if !isSupported(c.values, value) {
+			return c, "", fmt.Errorf("unsupported %s type '%s'. Supported types are: (%v)", c.name, value, strings.Join(c.values, ", "))
+		}
+
+		return c, value, nil
+	}
+
+	if plugin, ok := loadPlugins().components[name]; ok {
+		if len(args) > 1 {
+			return addableComponent{}, "", fmt.Errorf("component %s does not take a type", name)
+		}
+
+		return plugin.addable(answers[name], ask), "", nil
+	}
+
+	names := make([]string, 0, len(addableComponents))
+	for _, c := range addableComponents {
+		names = append(names, c.name)
+	}
+
+	for name := range loadPlugins().components {
+		names = append(names, name)
+	}
+
+	sort.Strings(names[len(addableComponents):])
+
+	return addableComponent{}, "", fmt.Errorf("unknown component '%s'. Supported components are: (%v)", args[0], strings.Join(names, ", "))
+}
+
+// runAdd adds component to the ignite project in dir, rendering it with the
+// templates in ts, and returns what it wrote. Progress is reported to out.
+//
+// It does the following steps:
+//
+//   - reads the project's options from its lock file.
+//   - plans the project with and without the component and keeps the files and
+//     directories only the former contains.
+//   - inserts the component's sections into the existing Makefile and README,
+//     leaving any section already present untouched. The lock file records
+//     them as generated, so that check still reports the user's edits.
+//   - resolves conflicts for the new files with the given strategy.
+//   - writes everything, together with the updated lock file and the base
+//     snapshots of the files it creates or updates, through a staging area so
+//     a failure leaves the project unchanged.
+//   - adds the Go modules the component needs with go get.
+func runAdd(ctx context.Context, dir string, ts *templateSet, component addableComponent, value, strategy string, out io.Writer) (*Result, error) {
+	lock, err := readLockFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	before := lock.initializer()
+	before.templates = ts
+
+	after := lock.initializer()
+	after.templates = ts
+	after.out = out
+
+	if err := component.apply(after, value); err != nil {
+		if errors.Is(err, errComponentPresent) {
+			return nil, fmt.Errorf("%s is already part of the project", component.name)
+		}
+
+		return nil, err
+	}
+
+	if err := after.validateComponents(); err != nil {
+		return nil, err
+	}
+
+	oldPlan, err := before.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	newPlan, err := after.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	existing := make(map[string]bool, len(oldPlan.items))
+	for _, item := range oldPlan.items {
+		existing[item.path] = true
+	}
+
+	added := &projectPlan{}
+	updated := &projectPlan{}
+	// generated holds the updated files as ignite generated them, without the
+	// user's edits, whose checksums are recorded in the lock file
+	generated := &projectPlan{}
+
+	for _, item := range newPlan.items {
+		if !existing[item.path] {
+			added.items = append(added.items, item)
+
+			continue
+		}
+
+		syntax, ok := blockFiles[item.path]
+		if !ok {
+			continue
+		}
+
+		block, ok := syntax.extractBlock(item.content, component.name)
+		if !ok {
+			continue
+		}
+
+		current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(item.path)))
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(out, "Skipping %s section: %s not found\n", component.name, item.path)
+
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.path, err)
+		}
+
+		content, changed := syntax.insertBlock(current, block, component.name)
+		if !changed {
+			continue
+		}
+
+		updated.items = append(updated.items, planItem{path: item.path, content: content, mode: item.mode})
+
+		// the user's edits to the file are not ignite's: record the section
+		// inserted into the file as generated, or else the template
+		pristine, err := readBase(dir, item.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the base snapshot of %s: %w", item.path, err)
+		}
+
+		if pristine != nil {
+			pristine, _ = syntax.insertBlock(pristine, block, component.name)
+		} else {
+			pristine = item.content
+		}
+
+		generated.items = append(generated.items, planItem{path: item.path, content: pristine, mode: item.mode})
+	}
+
+	conflicts, err := detectConflicts(added, os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	// the base snapshots of the other files stay those of the templates the
+	// project was generated or last upgraded with, so that upgrade still
+	// applies the changes of the templates since to them
+	snapshots := &projectPlan{items: append(added.items[:len(added.items):len(added.items)], generated.items...)}
+
+	added, err = resolveConflicts(added, conflicts, strategy, out)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &projectPlan{items: append(added.items, updated.items...)}
+
+	lock.record(after, &projectPlan{items: append(added.items[:len(added.items):len(added.items)], generated.items...)})
+
+	plan, err = appendMetadata(plan, lock, snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	stage, err := newStagingArea(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.discard()
+
+	if err := createDirectories(plan, DirFS(stage.dir)); err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", component.name, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("adding %s interrupted: %w", component.name, err)
+	}
+
+	if err := stage.commit(); err != nil {
+		return nil, err
+	}
+
+	var modules []string
+
+	for _, module := range after.goModules() {
+		if !isSupported(before.goModules(), module) {
+			modules = append(modules, module)
+		}
+	}
+
+	if err := addGoModules(ctx, dir, modules, out); err != nil {
+		return nil, fmt.Errorf("the files of %s were added, but: %w (run go get %s in the project)", component.name, err, strings.Join(modules, " "))
+	}
+
+	return after.result(".", plan), nil
+}
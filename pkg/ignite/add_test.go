@@ -0,0 +1,97 @@
+package ignite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	result, err := Add(context.Background(), dir, AddOptions{Component: "dockerfile"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var written []string
+
+	for _, f := range result.Files {
+		if !f.Metadata {
+			written = append(written, f.Path)
+		}
+	}
+
+	if got := strings.Join(written, ","); got != "Dockerfile,README.md,Makefile" {
+		t.Errorf("Add wrote %s, want Dockerfile,README.md,Makefile", got)
+	}
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !lock.Options.Dockerfile {
+		t.Error("dockerfile not recorded in the lock file")
+	}
+
+	report, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Clean {
+		t.Errorf("project not clean after add: %v", report.Issues)
+	}
+
+	if _, err := Add(context.Background(), dir, AddOptions{Component: "dockerfile"}); err == nil {
+		t.Error("adding the same component twice succeeded")
+	}
+}
+
+func TestAddKeepsUserEditsVisible(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+	makefile := filepath.Join(dir, "Makefile")
+
+	content, err := os.ReadFile(makefile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(makefile, append(content, "# my target\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Add(context.Background(), dir, AddOptions{Component: "dockerfile"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	content, err = os.ReadFile(makefile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(content), "# my target\n") {
+		t.Error("add dropped the user's edit")
+	}
+
+	report, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DriftIssue{{Kind: DriftModifiedFile, Path: "Makefile"}}
+	if len(report.Issues) != 1 || report.Issues[0] != want[0] {
+		t.Errorf("issues after add = %v, want %v", report.Issues, want)
+	}
+}
+
+func TestAddUnknownComponent(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "api", Database: "postgres", Controller: "http"})
+
+	if _, err := Add(context.Background(), dir, AddOptions{Component: "cache"}); err == nil {
+		t.Error("Add accepted an unknown component")
+	}
+}
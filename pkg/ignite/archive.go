@@ -0,0 +1,156 @@
+package ignite
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// ArchiveFS is an FS writing a project into a tar or zip stream. Archives are
+// write-only: reading one reports every file as missing, so nothing written to
+// it ever conflicts. Close must be called once the project is generated to
+// complete the archive; it does not close the underlying writer.
+type ArchiveFS struct {
+	// IncludeGit keeps the .git directory of the generated project in the
+	// archive. It is left out by default, as the repository git init creates
+	// while generating is empty and an archive is unpacked into a repository
+	// of its own.
+	IncludeGit bool
+
+	// dirs records the directories already in the archive, so that each is
+	// written once.
+	dirs    map[string]bool
+	modTime time.Time
+
+	writeDir  func(name string, perm fs.FileMode) error
+	writeFile func(name string, data []byte, perm fs.FileMode) error
+	finish    func() error
+}
+
+// NewTarFS returns an FS writing a tar archive to w. Wrap w with
+// compress/gzip for a .tar.gz.
+func NewTarFS(w io.Writer) *ArchiveFS {
+	tw := tar.NewWriter(w)
+	a := newArchiveFS(tw.Close)
+
+	a.writeDir = func(name string, perm fs.FileMode) error {
+		return tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name + "/",
+			Mode:     int64(perm.Perm()),
+			ModTime:  a.modTime,
+		})
+	}
+
+	a.writeFile = func(name string, data []byte, perm fs.FileMode) error {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(perm.Perm()),
+			Size:     int64(len(data)),
+			ModTime:  a.modTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write(data)
+
+		return err
+	}
+
+	return a
+}
+
+// NewZipFS returns an FS writing a zip archive to w.
+func NewZipFS(w io.Writer) *ArchiveFS {
+	zw := zip.NewWriter(w)
+	a := newArchiveFS(zw.Close)
+
+	a.writeDir = func(name string, perm fs.FileMode) error {
+		header := &zip.FileHeader{Name: name + "/", Modified: a.modTime}
+		header.SetMode(fs.ModeDir | perm.Perm())
+
+		_, err := zw.CreateHeader(header)
+
+		return err
+	}
+
+	a.writeFile = func(name string, data []byte, perm fs.FileMode) error {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.modTime}
+		header.SetMode(perm.Perm())
+
+		f, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(data)
+
+		return err
+	}
+
+	return a
+}
+
+func newArchiveFS(finish func() error) *ArchiveFS {
+	return &ArchiveFS{dirs: map[string]bool{".": true}, modTime: time.Now(), finish: finish}
+}
+
+// Open reports that name does not exist, except for the root directory.
+func (a *ArchiveFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return &memDir{info: memInfo{name: ".", mode: fs.ModeDir | 0o777, modTime: a.modTime}}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// MkdirAll adds the directory name, and those of its parents not added yet, to
+// the archive.
+func (a *ArchiveFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if a.dirs[name] {
+		return nil
+	}
+
+	if err := a.MkdirAll(path.Dir(name), perm); err != nil {
+		return err
+	}
+
+	if err := a.writeDir(name, perm); err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	a.dirs[name] = true
+
+	return nil
+}
+
+// WriteFile adds the file name to the archive.
+func (a *ArchiveFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := a.writeFile(name, data, perm); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// Close writes the end of the archive.
+func (a *ArchiveFS) Close() error {
+	return a.finish()
+}
@@ -0,0 +1,207 @@
+package ignite
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+// archiveEntry is an entry read back from an archive.
+type archiveEntry struct {
+	content string
+	mode    fs.FileMode
+}
+
+// writeArchive writes a small project into a.
+func writeArchive(t *testing.T, a *ArchiveFS) {
+	t.Helper()
+
+	steps := []error{
+		a.MkdirAll("api/cmd/server", 0o755),
+		a.MkdirAll("api/cmd", 0o755),
+		a.WriteFile("api/cmd/server/main.go", []byte("package main\n"), 0o644),
+		a.WriteFile("api/run.sh", []byte("#!/bin/sh\n"), 0o755),
+		a.Close(),
+	}
+
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// wantArchive is the content of the archive written by writeArchive. Every
+// directory is written once.
+var wantArchive = map[string]archiveEntry{
+	"api/":                   {mode: fs.ModeDir | 0o755},
+	"api/cmd/":               {mode: fs.ModeDir | 0o755},
+	"api/cmd/server/":        {mode: fs.ModeDir | 0o755},
+	"api/cmd/server/main.go": {content: "package main\n", mode: 0o644},
+	"api/run.sh":             {content: "#!/bin/sh\n", mode: 0o755},
+}
+
+func checkArchive(t *testing.T, got map[string]archiveEntry) {
+	t.Helper()
+
+	if len(got) != len(wantArchive) {
+		t.Errorf("archive has %d entries, want %d: %v", len(got), len(wantArchive), got)
+	}
+
+	for name, want := range wantArchive {
+		if entry, ok := got[name]; !ok {
+			t.Errorf("%s missing from the archive", name)
+		} else if entry != want {
+			t.Errorf("%s = %+v, want %+v", name, entry, want)
+		}
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	var buf bytes.Buffer
+
+	writeArchive(t, NewTarFS(&buf))
+
+	got := make(map[string]archiveEntry)
+	tr := tar.NewReader(&buf)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := got[header.Name]; ok {
+			t.Errorf("%s written twice", header.Name)
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got[header.Name] = archiveEntry{content: string(content), mode: header.FileInfo().Mode()}
+	}
+
+	checkArchive(t, got)
+}
+
+func TestZipFS(t *testing.T) {
+	var buf bytes.Buffer
+
+	writeArchive(t, NewZipFS(&buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]archiveEntry)
+
+	for _, f := range zr.File {
+		if _, ok := got[f.Name]; ok {
+			t.Errorf("%s written twice", f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(rc)
+		rc.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got[f.Name] = archiveEntry{content: string(content), mode: f.Mode()}
+	}
+
+	checkArchive(t, got)
+}
+
+func TestArchiveFSOpen(t *testing.T) {
+	a := NewTarFS(io.Discard)
+
+	if err := a.MkdirAll("api", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.WriteFile("api/go.mod", []byte("module api\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := fs.Stat(a, "."); err != nil || !info.IsDir() {
+		t.Errorf("root is not a directory: %v", err)
+	}
+
+	if _, err := fs.ReadFile(a, "api/go.mod"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("reading a written file: got %v, want %v", err, fs.ErrNotExist)
+	}
+
+	for _, name := range []string{"../x", "/abs", "."} {
+		if err := a.WriteFile(name, nil, 0o644); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("WriteFile(%q) = %v, want %v", name, err, fs.ErrInvalid)
+		}
+	}
+}
+
+func TestArchiveGitDirectory(t *testing.T) {
+	for _, includeGit := range []bool{false, true} {
+		var buf bytes.Buffer
+
+		a := NewZipFS(&buf)
+		a.IncludeGit = includeGit
+
+		// projects are written into a directory of the archive
+		stage, err := newStagingAreaFor(subDir(a, "api"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		writeStaged(t, stage.dir, map[string]string{
+			"go.mod":              "module api\n",
+			".git/HEAD":           "ref: refs/heads/main\n",
+			"vendor/x/.git/HEAD":  "ref: refs/heads/main\n",
+			".gitignore":          "bin/\n",
+			"internal/api/api.go": "package api\n",
+		})
+
+		if err := stage.commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		stage.discard()
+
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make(map[string]bool)
+		for _, f := range zr.File {
+			names[f.Name] = true
+		}
+
+		if names["api/.git/HEAD"] != includeGit {
+			t.Errorf("IncludeGit %v: api/.git/HEAD in the archive = %v", includeGit, names["api/.git/HEAD"])
+		}
+
+		for _, name := range []string{"api/go.mod", "api/.gitignore", "api/internal/api/api.go", "api/vendor/x/.git/HEAD"} {
+			if !names[name] {
+				t.Errorf("IncludeGit %v: %s missing from the archive", includeGit, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ignite
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// blockSyntax describes how component sections are delimited in a generated
+// file. Each section generated for a component sits between a begin and an end
+// marker carrying the component's name, which lets ignite find and add sections
+// in files the user has since edited.
+type blockSyntax struct {
+	begin string
+	end   string
+	// anchor, if present in a file, marks where new sections are inserted.
+	// Otherwise they are appended to the end of the file.
+	anchor string
+}
+
+// blockFiles lists the generated files that contain component sections.
+var blockFiles = map[string]blockSyntax{
+	"Makefile": {
+		begin: "# ignite:begin %s",
+		end:   "# ignite:end %s",
+	},
+	"README.md": {
+		begin:  "<!-- ignite:begin %s -->",
+		end:    "<!-- ignite:end %s -->",
+		anchor: "<!-- ignite:components -->",
+	},
+}
+
+// extractBlock returns the section for component in content, markers included,
+// and whether it was found.
+func (s blockSyntax) extractBlock(content []byte, component string) ([]byte, bool) {
+	begin := []byte(fmt.Sprintf(s.begin, component))
+	end := []byte(fmt.Sprintf(s.end, component))
+
+	start := bytes.Index(content, begin)
+	if start < 0 {
+		return nil, false
+	}
+
+	stop := bytes.Index(content[start:], end)
+	if stop < 0 {
+		return nil, false
+	}
+
+	return content[start : start+stop+len(end)], true
+}
+
+// insertBlock adds block to content unless a section for component is already
+// present, in which case content is returned unchanged so user edits to it are
+// kept. The block is inserted before the anchor line if there is one and
+// appended otherwise. The second return value reports whether content changed.
+func (s blockSyntax) insertBlock(content, block []byte, component string) ([]byte, bool) {
+	if _, ok := s.extractBlock(content, component); ok {
+		return content, false
+	}
+
+	var out bytes.Buffer
+
+	if s.anchor != "" {
+		if i := bytes.Index(content, []byte(s.anchor)); i >= 0 {
+			out.Write(content[:i])
+			out.Write(block)
+			out.WriteString("\n\n")
+			out.Write(content[i:])
+
+			return out.Bytes(), true
+		}
+	}
+
+	out.Write(bytes.TrimRight(content, "\n"))
+
+	if out.Len() > 0 {
+		out.WriteString("\n\n")
+	}
+
+	out.Write(block)
+	out.WriteString("\n")
+
+	return out.Bytes(), true
+}
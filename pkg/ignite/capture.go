@@ -0,0 +1,497 @@
+package ignite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// captureExcluded are the project paths never captured: version control and
+// ignite metadata, and the module files ignite creates with go mod init.
+var captureExcluded = []string{".git", baseDir, path.Dir(baseDir), lockFileName, "go.mod", "go.sum"}
+
+// captureVars are the project values replaced with template variables when a
+// project is captured.
+type captureVars struct {
+	module string
+	name   string
+	dbType string
+}
+
+// CaptureOptions configures Capture. Empty fields are read from the project's
+// lock file or go.mod.
+type CaptureOptions struct {
+	Module string
+	// Name defaults to the last element of the module path.
+	Name     string
+	Database string
+}
+
+// Capture writes a template pack reproducing the project in dir to out, which
+// must not exist or be empty, and returns the number of files captured.
+//
+// Every file not ignored by the project's .gitignore files becomes a template,
+// with the module path, the project name and the database type replaced with
+// {{ .ModulePath }}, {{ .ProjectName }} and {{ .DBType }} where they refer to
+// the project rather than a dependency. The version control and ignite metadata, go.mod and go.sum are never captured.
+func Capture(dir, out string, opts CaptureOptions) (int, error) {
+	vars, err := captureVarsFor(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.Module != "" {
+		vars.module = opts.Module
+		vars.name = path.Base(opts.Module)
+	}
+
+	if opts.Name != "" {
+		vars.name = opts.Name
+	}
+
+	if opts.Database != "" {
+		vars.dbType = opts.Database
+	}
+
+	return capturePack(dir, out, vars)
+}
+
+// captureVarsFor reads the module path, name and database type of the project
+// in dir from its lock file or, failing that, its go.mod.
+func captureVarsFor(dir string) (captureVars, error) {
+	if lock, err := readLockFile(dir); err == nil {
+		return captureVars{module: lock.Project.Module, name: lock.Project.Name, dbType: lock.Options.Database}, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return captureVars{}, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			module := strings.Trim(fields[1], `"`)
+
+			return captureVars{module: module, name: path.Base(module)}, nil
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return captureVars{}, err
+	}
+
+	return captureVars{name: filepath.Base(abs)}, nil
+}
+
+// capturePack writes a template pack reproducing the project in dir to out and
+// returns the number of files captured. The pack is loaded back before
+// returning, so a pack ignite cannot use is reported as an error.
+func capturePack(dir, out string, vars captureVars) (int, error) {
+	if entries, err := os.ReadDir(out); err == nil && len(entries) > 0 {
+		return 0, fmt.Errorf("output directory %s is not empty", out)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open project: %w", err)
+	}
+
+	if !info.IsDir() {
+		return 0, fmt.Errorf("project %s is not a directory", dir)
+	}
+
+	// an output directory inside the project must not capture itself
+	outRel := ""
+	if absDir, err := filepath.Abs(dir); err == nil {
+		if absOut, err := filepath.Abs(out); err == nil {
+			if rel, err := filepath.Rel(absDir, absOut); err == nil && filepath.IsLocal(rel) {
+				outRel = filepath.ToSlash(rel)
+			}
+		}
+	}
+
+	var (
+		ignore    gitignore
+		structure []capturedEntry
+		files     int
+	)
+
+	err = filepath.WalkDir(dir, func(fullPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, fullPath)
+		if err != nil || rel == "." {
+			if d.IsDir() {
+				return ignore.load(dir, "")
+			}
+
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if isSupported(captureExcluded, rel) || rel == outRel || ignore.ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			entries, err := os.ReadDir(fullPath)
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 {
+				structure = append(structure, capturedEntry{Path: vars.templatize(rel), Type: entryTypeDir})
+			}
+
+			return ignore.load(fullPath, rel)
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if err := captureFile(fullPath, filepath.Join(out, filesDir, filepath.FromSlash(rel)+templateSuffix), vars); err != nil {
+			return fmt.Errorf("failed to capture %s: %w", rel, err)
+		}
+
+		// templates keep the captured path, so templated paths name them
+		// explicitly
+		entry := capturedEntry{Path: vars.templatize(rel), Type: entryTypeFile}
+		if entry.Path != rel {
+			entry.Template = rel
+		}
+
+		structure = append(structure, entry)
+		files++
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeCapturedManifest(out, dir, structure); err != nil {
+		return 0, err
+	}
+
+	ts, err := newTemplateSet(out)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := loadManifest(ts); err != nil {
+		return 0, fmt.Errorf("captured pack is not usable: %w", err)
+	}
+
+	return files, nil
+}
+
+// captureFile writes the template for the project file src to dst. Text files
+// have literal template delimiters escaped and the project's values replaced
+// with template variables; binary files are copied as is, with a front matter
+// marking them raw. Executable files get a front matter keeping their mode.
+func captureFile(src, dst string, vars captureVars) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	var header string
+	if info.Mode().Perm()&0o111 != 0 {
+		header = fmt.Sprintf("mode: \"%04o\"\n", info.Mode().Perm())
+	}
+
+	body := string(content)
+	if bytes.IndexByte(content, 0) < 0 && utf8.Valid(content) {
+		body = vars.templatize(body)
+	} else {
+		header += "raw: true\n"
+	}
+
+	if header != "" || strings.HasPrefix(body, frontMatterDelim+"\n") || strings.HasPrefix(body, frontMatterDelim+"\r\n") {
+		body = frontMatterDelim + "\n" + header + frontMatterDelim + "\n" + body
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, []byte(body), 0o644)
+}
+
+// slashPath matches slash-separated paths, like import paths and the paths of
+// project files.
+var slashPath = regexp.MustCompile(`[\w.~-]+(?:/[\w.~-]+)+`)
+
+// templatize escapes the template delimiters in s and replaces the project's
+// values with template variables:
+//
+//   - the module path, on its own or at the start of an import path, with
+//     {{ .ModulePath }}.
+//   - the project name as a whole word with {{ .ProjectName }}.
+//   - the database type as a word of a project path, like
+//     internal/postgres/queries, or as a Go package name, in package clauses
+//     and qualified identifiers like postgres.New, with {{ .DBType }}.
+//
+// The paths of dependencies, those whose first element has a dot like
+// github.com/go-sql-driver/mysql, are left as they are.
+func (v captureVars) templatize(s string) string {
+	s = escapeDelims(s)
+
+	var b strings.Builder
+
+	last := 0
+
+	for _, loc := range slashPath.FindAllStringIndex(s, -1) {
+		b.WriteString(v.templatizeText(s[last:loc[0]]))
+		b.WriteString(v.templatizePath(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+
+	b.WriteString(v.templatizeText(s[last:]))
+
+	return b.String()
+}
+
+// templatizePath templatizes the slash-separated path p.
+func (v captureVars) templatizePath(p string) string {
+	prefix := ""
+
+	switch {
+	case v.module != "" && (p == v.module || strings.HasPrefix(p, v.module+"/")):
+		prefix, p = "{{ .ModulePath }}", strings.TrimPrefix(p, v.module)
+	case isModulePath(p):
+		return p
+	}
+
+	p = replaceWord(p, v.name, "{{ .ProjectName }}")
+	p = replaceWord(p, v.dbType, "{{ .DBType }}")
+
+	return prefix + p
+}
+
+// templatizeText templatizes text outside of slash-separated paths.
+func (v captureVars) templatizeText(s string) string {
+	s = replaceWord(s, v.name, "{{ .ProjectName }}")
+
+	if v.dbType == "" {
+		return s
+	}
+
+	// package clauses and identifiers qualified with the package name, which
+	// are always exported
+	word := regexp.QuoteMeta(v.dbType)
+	re := regexp.MustCompile(`\b(package\s+)` + word + `\b|\b` + word + `(\.[A-Z])`)
+
+	return re.ReplaceAllString(s, "${1}{{ .DBType }}${2}")
+}
+
+// escapeDelims escapes the template delimiters in s so it renders as is.
+func escapeDelims(s string) string {
+	return strings.ReplaceAll(s, "{{", `{{ "{{" }}`)
+}
+
+func replaceWord(s, word, replacement string) string {
+	if word == "" {
+		return s
+	}
+
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(word) + `\b`)
+
+	return re.ReplaceAllLiteralString(s, replacement)
+}
+
+type capturedEntry struct {
+	Path     string `yaml:"path"`
+	Type     string `yaml:"type"`
+	Template string `yaml:"template,omitempty"`
+}
+
+// writeCapturedManifest writes the manifest of a captured pack.
+func writeCapturedManifest(out, dir string, structure []capturedEntry) error {
+	content, err := yaml.Marshal(struct {
+		Version   int             `yaml:"version"`
+		Structure []capturedEntry `yaml:"structure"`
+	}{manifestVersion, structure})
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	header := fmt.Sprintf("# Project blueprint captured from %s by ignite template capture.\n", filepath.Base(dir))
+
+	if err := os.MkdirAll(out, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", out, err)
+	}
+
+	return os.WriteFile(filepath.Join(out, manifestName), append([]byte(header), content...), 0o644)
+}
+
+// gitignore matches paths against the patterns of the .gitignore files of a
+// project. It supports comments, negation (!), directory-only patterns
+// (trailing /), anchored patterns (containing a /) and the * ? [...] and **
+// wildcards.
+type gitignore struct {
+	rules []gitignoreRule
+}
+
+type gitignoreRule struct {
+	// base is the directory of the .gitignore file, relative to the project
+	// root, empty for the root.
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the path relative to base, the others the last
+	// element of the path.
+	anchored bool
+}
+
+// load reads the .gitignore file of the directory fullPath, at rel in the
+// project, if there is one.
+func (g *gitignore) load(fullPath, rel string) error {
+	f, err := os.Open(filepath.Join(fullPath, ".gitignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: rel}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+
+		rule.pattern = pattern
+		g.rules = append(g.rules, rule)
+	}
+
+	return nil
+}
+
+// ignored reports whether the project path rel is ignored. The last matching
+// rule wins.
+func (g *gitignore) ignored(rel string, isDir bool) bool {
+	ignored := false
+
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// globToRegexp translates a gitignore glob to a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+
+				continue
+			}
+
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package ignite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitignore(t *testing.T) {
+	root := t.TempDir()
+
+	writeGitignore := func(dir, content string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(root, dir, ".gitignore"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeGitignore(".", `# build output
+*.log
+!keep.log
+bin/
+/tmp
+docs/*.pdf
+**/cache
+vendor/**
+file?.txt
+[abc].md
+`)
+	writeGitignore("sub", "local.txt\n/anchored\n")
+
+	var g gitignore
+	for _, dir := range []string{".", "sub"} {
+		rel := dir
+		if dir == "." {
+			rel = ""
+		}
+
+		if err := g.load(filepath.Join(root, dir), rel); err != nil {
+			t.Fatalf("load %s: %v", dir, err)
+		}
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "app.log", want: true},
+		{path: "nested/dir/app.log", want: true},
+		{path: "keep.log", want: false},
+		{path: "bin", isDir: true, want: true},
+		{path: "bin", isDir: false, want: false},
+		{path: "cmd/bin", isDir: true, want: true},
+		{path: "tmp", isDir: true, want: true},
+		{path: "cmd/tmp", isDir: true, want: false},
+		{path: "docs/guide.pdf", want: true},
+		{path: "docs/nested/guide.pdf", want: false},
+		{path: "cache", isDir: true, want: true},
+		{path: "a/b/cache", isDir: true, want: true},
+		{path: "vendor/github.com/x/y.go", want: true},
+		{path: "file1.txt", want: true},
+		{path: "file10.txt", want: false},
+		{path: "a.md", want: true},
+		{path: "d.md", want: false},
+		{path: "sub/local.txt", want: true},
+		{path: "sub/deep/local.txt", want: true},
+		{path: "local.txt", want: false},
+		{path: "sub/anchored", want: true},
+		{path: "sub/deep/anchored", want: false},
+		{path: "main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := g.ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestGitignoreMissingFile(t *testing.T) {
+	var g gitignore
+
+	if err := g.load(t.TempDir(), ""); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if g.ignored("anything", false) {
+		t.Error("path ignored without a .gitignore file")
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{glob: "*.go", want: `[^/]*\.go`},
+		{glob: "a?c", want: `a[^/]c`},
+		{glob: "**/x", want: `(.*/)?x`},
+		{glob: "x/**", want: `x(/.*)?`},
+		{glob: "[!a]b", want: `[^a]b`},
+		{glob: "[ab", want: `\[ab`},
+	}
+
+	for _, tt := range tests {
+		if got := globToRegexp(tt.glob); got != tt.want {
+			t.Errorf("globToRegexp(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestTemplatize(t *testing.T) {
+	vars := captureVars{module: "github.com/acme/api", name: "api", dbType: "mysql"}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `import "github.com/acme/api/internal/mysql"`, want: `import "{{ .ModulePath }}/internal/{{ .DBType }}"`},
+		{in: "module github.com/acme/api", want: "module {{ .ModulePath }}"},
+		{in: "internal/mysql/queries/users.sql", want: "internal/{{ .DBType }}/queries/users.sql"},
+		{in: "package mysql", want: "package {{ .DBType }}"},
+		{in: "store := mysql.NewStore(db)", want: "store := {{ .DBType }}.NewStore(db)"},
+		{in: "# api", want: "# {{ .ProjectName }}"},
+		{in: "cmd/api/main.go", want: "cmd/{{ .ProjectName }}/main.go"},
+		{in: "{{ .Name }}", want: `{{ "{{" }} .Name }}`},
+		// dependencies keep their paths
+		{in: `_ "github.com/go-sql-driver/mysql"`, want: `_ "github.com/go-sql-driver/mysql"`},
+		{in: "require github.com/acme/api-client v1.0.0", want: "require github.com/acme/api-client v1.0.0"},
+		{in: "see https://example.com/docs/api", want: "see https://example.com/docs/api"},
+		// only package names and paths take the database type
+		{in: "FROM mysql:8", want: "FROM mysql:8"},
+		{in: "the mysql driver", want: "the mysql driver"},
+	}
+
+	for _, tt := range tests {
+		if got := vars.templatize(tt.in); got != tt.want {
+			t.Errorf("templatize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCaptureKeepsDependencies(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "pack")
+
+	writeStaged(t, dir, map[string]string{
+		"go.mod": "module github.com/acme/api\n",
+		"internal/mysql/store.go": "package mysql\n\nimport (\n\t\"database/sql\"\n\n\t_ \"github.com/go-sql-driver/mysql\"\n)\n\n" +
+			"func Open(dsn string) (*sql.DB, error) { return sql.Open(\"mysql\", dsn) }\n",
+	})
+
+	if _, err := Capture(dir, out, CaptureOptions{Database: "mysql"}); err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(out, filesDir, "internal", "mysql", "store.go"+templateSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "package {{ .DBType }}\n\nimport (\n\t\"database/sql\"\n\n\t_ \"github.com/go-sql-driver/mysql\"\n)\n\n" +
+		"func Open(dsn string) (*sql.DB, error) { return sql.Open(\"mysql\", dsn) }\n"
+	if string(content) != want {
+		t.Errorf("captured template = %q, want %q", content, want)
+	}
+
+	manifest, _ := os.ReadFile(filepath.Join(out, manifestName))
+	if !strings.Contains(string(manifest), "path: internal/{{ .DBType }}/store.go") {
+		t.Errorf("manifest does not template the database path:\n%s", manifest)
+	}
+}
@@ -0,0 +1,156 @@
+package ignite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of drift reported by Check.
+const (
+	DriftMissingDirectory = "missing_directory"
+	DriftMissingFile      = "missing_file"
+	DriftModifiedFile     = "modified_file"
+)
+
+// DriftReport is the outcome of Check.
+type DriftReport struct {
+	Project string       `json:"project"`
+	Path    string       `json:"path"`
+	Clean   bool         `json:"clean"`
+	Issues  []DriftIssue `json:"issues"`
+}
+
+// DriftIssue is a difference between a project and its blueprint. Kind is one
+// of the Drift constants.
+type DriftIssue struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+}
+
+// CheckOptions configures Check.
+type CheckOptions struct {
+	// Templates, if not empty, replaces the template pack recorded in the
+	// project's lock file (see Options.Templates).
+	Templates string
+	// Variables overrides the built-in variables (database, controller,
+	// workflow, dockerfile) recorded in the project's lock file or, for
+	// projects without one, gives the options they were generated with.
+	Variables map[string]string
+}
+
+// Check compares the project in dir with what ignite would generate for it,
+// and reports missing directories, deleted files and modified template files.
+func Check(dir string, opts CheckOptions) (*DriftReport, error) {
+	for name := range opts.Variables {
+		if !isSupported(builtinVariables, name) {
+			return nil, fmt.Errorf("unknown variable %s (one of: %s)", name, strings.Join(builtinVariables, ", "))
+		}
+	}
+
+	ts, err := projectTemplateSet(opts.Templates, dir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return runCheck(dir, ts, func(p *projectInitializer) error {
+		values := make(map[string]any, len(opts.Variables))
+
+		for name, raw := range opts.Variables {
+			v := builtinVariable(name)
+			if v.Type != variableBool {
+				// the components are validated with the other options
+				values[name] = raw
+
+				continue
+			}
+
+			value, err := v.Parse(raw)
+			if err != nil {
+				return fmt.Errorf("invalid value for variable %s: %w", name, err)
+			}
+
+			values[name] = value
+		}
+
+		p.applyVariables(values)
+
+		return nil
+	})
+}
+
+// runCheck compares the project in dir with its blueprint as rendered from the
+// templates in ts.
+//
+// The project's options come from its lock file, if any, and are then passed to
+// override. Projects without a lock file are checked with the module name set
+// to the directory name.
+func runCheck(dir string, ts *templateSet, override func(p *projectInitializer) error) (*DriftReport, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &lockFile{Project: lockProject{Name: filepath.Base(dir), Module: filepath.Base(dir)}}
+
+	if _, err := os.Stat(filepath.Join(dir, lockFileName)); err == nil {
+		lock, err = readLockFile(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := lock.initializer()
+	p.templates = ts
+
+	if err := override(p); err != nil {
+		return nil, err
+	}
+
+	if err := p.validateComponents(); err != nil {
+		return nil, err
+	}
+
+	plan, err := p.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	report := &DriftReport{Project: lock.Project.Name, Path: dir, Issues: []DriftIssue{}}
+
+	for _, item := range plan.items {
+		fullPath := filepath.Join(dir, filepath.FromSlash(item.path))
+
+		if item.isDir {
+			if info, err := os.Stat(fullPath); err != nil || !info.IsDir() {
+				report.Issues = append(report.Issues, DriftIssue{Kind: DriftMissingDirectory, Path: item.path})
+			}
+
+			continue
+		}
+
+		current, err := os.ReadFile(fullPath)
+		if errors.Is(err, os.ErrNotExist) {
+			report.Issues = append(report.Issues, DriftIssue{Kind: DriftMissingFile, Path: item.path})
+
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.path, err)
+		}
+
+		expected, ok := lock.Files[item.path]
+		if !ok {
+			expected = checksum(item.content)
+		}
+
+		if checksum(current) != expected {
+			report.Issues = append(report.Issues, DriftIssue{Kind: DriftModifiedFile, Path: item.path})
+		}
+	}
+
+	report.Clean = len(report.Issues) == 0
+
+	return report, nil
+}
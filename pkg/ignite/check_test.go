@@ -0,0 +1,111 @@
+package ignite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	opts := Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"}
+
+	tests := []struct {
+		name   string
+		change func(dir string) error
+		want   []DriftIssue
+	}{
+		{
+			name:   "unchanged",
+			change: func(string) error { return nil },
+		},
+		{
+			name: "modified file",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "Makefile"), []byte("all:\n"), 0o644)
+			},
+			want: []DriftIssue{{Kind: DriftModifiedFile, Path: "Makefile"}},
+		},
+		{
+			name: "deleted file",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "README.md"))
+			},
+			want: []DriftIssue{{Kind: DriftMissingFile, Path: "README.md"}},
+		},
+		{
+			name: "deleted directory",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "internal", "services"))
+			},
+			want: []DriftIssue{{Kind: DriftMissingDirectory, Path: "internal/services"}},
+		},
+		{
+			name: "untracked file",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "NOTES.md"), []byte("notes\n"), 0o644)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writePlannedProject(t, opts)
+
+			if err := tt.change(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := Check(dir, CheckOptions{})
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+
+			if report.Clean != (len(tt.want) == 0) {
+				t.Errorf("Clean = %v with issues %v", report.Clean, report.Issues)
+			}
+
+			if len(report.Issues) != len(tt.want) {
+				t.Fatalf("issues = %v, want %v", report.Issues, tt.want)
+			}
+
+			for i, issue := range report.Issues {
+				if issue != tt.want[i] {
+					t.Errorf("issue %d = %v, want %v", i, issue, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOverride(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	// checked as a mysql project, the postgres files are not expected and
+	// the mysql directories are missing
+	report, err := Check(dir, CheckOptions{Variables: map[string]string{"database": "mysql"}})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if report.Clean {
+		t.Fatal("project reported clean against another database")
+	}
+
+	found := false
+
+	for _, issue := range report.Issues {
+		if issue == (DriftIssue{Kind: DriftMissingDirectory, Path: "internal/mysql/queries"}) {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("issues = %v, want internal/mysql/queries missing", report.Issues)
+	}
+}
+
+func TestCheckUnknownVariable(t *testing.T) {
+	if _, err := Check(t.TempDir(), CheckOptions{Variables: map[string]string{"team": "payments"}}); err == nil {
+		t.Error("Check accepted a variable other than the built-in ones")
+	}
+}
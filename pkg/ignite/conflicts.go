@@ -0,0 +1,136 @@
+package ignite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Strategies for files that already exist in the project directory, named
+// after the flags selecting them.
+const (
+	// ConflictAbort refuses to generate the project, listing every conflict.
+	ConflictAbort = ""
+	// ConflictForce overwrites existing files.
+	ConflictForce = "force"
+	// ConflictSkipExisting keeps existing files and only writes the others.
+	ConflictSkipExisting = "skip-existing"
+	// ConflictMerge keeps existing files and writes the new version next to
+	// them, as <file>.ignite-new.
+	ConflictMerge = "merge"
+)
+
+// mergeSuffix is appended to the name of the sidecar file written next to an
+// existing file when the merge strategy is used.
+const mergeSuffix = ".ignite-new"
+
+// conflictError lists every planned file that already exists with different
+// content.
+type conflictError struct {
+	paths []string
+}
+
+func (e *conflictError) Error() string {
+	return fmt.Sprintf(
+		"refusing to overwrite %d existing file(s):\n  %s\nuse --force to overwrite, --skip-existing to keep them or --merge to write %s files next to them",
+		len(e.paths), strings.Join(e.paths, "\n  "), mergeSuffix,
+	)
+}
+
+// detectConflicts compares the plan with what is already in target.
+//
+// It returns the paths of planned files that already exist with different
+// content, sorted. Files that exist with identical content are not conflicts.
+// A planned file whose path is an existing directory, or a planned directory
+// whose path is an existing file, is reported as an error since no strategy can
+// resolve it.
+func detectConflicts(plan *projectPlan, target fs.FS) ([]string, error) {
+	var conflicts []string
+
+	for _, item := range plan.items {
+		info, err := fs.Stat(target, item.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", item.path, err)
+		}
+
+		if item.isDir {
+			if !info.IsDir() {
+				return nil, fmt.Errorf("cannot create directory %s: a file with that name already exists", item.path)
+			}
+
+			continue
+		}
+
+		if info.IsDir() {
+			return nil, fmt.Errorf("cannot create file %s: a directory with that name already exists", item.path)
+		}
+
+		existing, err := fs.ReadFile(target, item.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.path, err)
+		}
+
+		if !bytes.Equal(existing, item.content) {
+			conflicts = append(conflicts, item.path)
+		}
+	}
+
+	sort.Strings(conflicts)
+
+	return conflicts, nil
+}
+
+// resolveConflicts applies strategy to the plan given the conflicting paths
+// reported by detectConflicts, and returns the plan to write. What happens to
+// each conflicting file is reported to out.
+//
+//   - ConflictAbort: returns a conflictError listing every conflicting path.
+//   - ConflictForce: returns the plan unchanged so existing files are
+//     overwritten.
+//   - ConflictSkipExisting: drops the conflicting files from the plan.
+//   - ConflictMerge: writes the new content to a sidecar file named
+//     <path>.ignite-new instead, leaving the existing file untouched.
+func resolveConflicts(plan *projectPlan, conflicts []string, strategy string, out io.Writer) (*projectPlan, error) {
+	if len(conflicts) == 0 || strategy == ConflictForce {
+		return plan, nil
+	}
+
+	if strategy == ConflictAbort {
+		return nil, &conflictError{paths: conflicts}
+	}
+
+	conflicting := make(map[string]bool, len(conflicts))
+	for _, path := range conflicts {
+		conflicting[path] = true
+	}
+
+	resolved := &projectPlan{items: make([]planItem, 0, len(plan.items))}
+
+	for _, item := range plan.items {
+		if item.isDir || !conflicting[item.path] {
+			resolved.items = append(resolved.items, item)
+
+			continue
+		}
+
+		switch strategy {
+		case ConflictSkipExisting:
+			fmt.Fprintf(out, "Skipping existing file: %s\n", item.path)
+		case ConflictMerge:
+			fmt.Fprintf(out, "Existing file %s kept, new version written to %s%s\n", item.path, item.path, mergeSuffix)
+
+			item.path += mergeSuffix
+			resolved.items = append(resolved.items, item)
+		default:
+			return nil, fmt.Errorf("unknown conflict strategy %q", strategy)
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,141 @@
+package ignite
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func conflictTarget(t *testing.T) *MemFS {
+	t.Helper()
+
+	fsys := NewMemFS()
+
+	steps := []error{
+		fsys.MkdirAll("cmd", 0o755),
+		fsys.WriteFile("README.md", []byte("my notes\n"), 0o644),
+		fsys.WriteFile("Makefile", []byte("build:\n"), 0o644),
+	}
+
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return fsys
+}
+
+func conflictPlan() *projectPlan {
+	return &projectPlan{items: []planItem{
+		{path: "cmd", isDir: true},
+		{path: "README.md", content: []byte("# api\n")},
+		{path: "Makefile", content: []byte("build:\n")},
+		{path: "go.mod", content: []byte("module api\n")},
+	}}
+}
+
+func planPaths(plan *projectPlan) string {
+	paths := make([]string, 0, len(plan.items))
+	for _, item := range plan.items {
+		paths = append(paths, item.path)
+	}
+
+	return strings.Join(paths, ",")
+}
+
+func TestDetectConflicts(t *testing.T) {
+	conflicts, err := detectConflicts(conflictPlan(), conflictTarget(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Makefile exists with the planned content and cmd is a directory
+	if got := strings.Join(conflicts, ","); got != "README.md" {
+		t.Errorf("conflicts = %q, want README.md", got)
+	}
+}
+
+func TestDetectConflictsKindMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		item planItem
+	}{
+		{name: "file over directory", item: planItem{path: "cmd", content: []byte("x")}},
+		{name: "directory over file", item: planItem{path: "README.md", isDir: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := detectConflicts(&projectPlan{items: []planItem{tt.item}}, conflictTarget(t))
+			if err == nil {
+				t.Fatal("detectConflicts succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     string
+		wantErr  bool
+	}{
+		{strategy: ConflictAbort, wantErr: true},
+		{strategy: ConflictForce, want: "cmd,README.md,Makefile,go.mod"},
+		{strategy: ConflictSkipExisting, want: "cmd,Makefile,go.mod"},
+		{strategy: ConflictMerge, want: "cmd,README.md.ignite-new,Makefile,go.mod"},
+		{strategy: "rename", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("strategy "+tt.strategy, func(t *testing.T) {
+			plan, err := resolveConflicts(conflictPlan(), []string{"README.md"}, tt.strategy, io.Discard)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resolveConflicts = %s, want an error", planPaths(plan))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := planPaths(plan); got != tt.want {
+				t.Errorf("plan = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveConflictsAbortListsEveryPath(t *testing.T) {
+	_, err := resolveConflicts(conflictPlan(), []string{"Makefile", "README.md"}, ConflictAbort, io.Discard)
+
+	var conflictErr *conflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("error = %v, want a conflictError", err)
+	}
+
+	for _, path := range []string{"Makefile", "README.md"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("error %q does not name %s", err, path)
+		}
+	}
+}
+
+func TestResolveConflictsWithoutConflicts(t *testing.T) {
+	plan := conflictPlan()
+
+	resolved, err := resolveConflicts(plan, nil, ConflictAbort, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved != plan {
+		t.Error("plan without conflicts was rebuilt")
+	}
+}
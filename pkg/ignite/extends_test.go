@@ -0,0 +1,93 @@
+package ignite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenPackChain(t *testing.T) {
+	root := t.TempDir()
+
+	writeStaged(t, root, map[string]string{
+		"company/manifest.yaml":           "version: 1\nextends: ../base\n",
+		"company/files/README.md.tmpl":    "company\n",
+		"base/files/README.md.tmpl":       "base\n",
+		"base/files/CONTRIBUTING.md.tmpl": "base\n",
+	})
+
+	ts, err := newTemplateSet(filepath.Join(root, "company"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"README.md": "company\n", "CONTRIBUTING.md": "base\n"} {
+		content, _, err := ts.lookup("files/" + name + templateSuffix)
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v, want %q", name, content, err, want)
+		}
+	}
+}
+
+func TestOpenPackChainErrors(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"self/manifest.yaml":    "version: 1\nextends: ../self\n",
+		"a/manifest.yaml":       "version: 1\nextends: ../b\n",
+		"b/manifest.yaml":       "version: 1\nextends: " + filepath.Join(root, "a") + "\n",
+		"missing/manifest.yaml": "version: 1\nextends: ../nowhere\n",
+		"invalid/manifest.yaml": "extends: [a, b]\n",
+	}
+
+	// a chain one pack longer than allowed
+	for i := 0; i <= maxExtendsDepth; i++ {
+		files[fmt.Sprintf("deep%d/manifest.yaml", i)] = fmt.Sprintf("version: 1\nextends: ../deep%d\n", i+1)
+	}
+
+	writeStaged(t, root, files)
+
+	tests := []struct {
+		pack    string
+		wantErr string
+	}{
+		{pack: "self", wantErr: "cycle"},
+		{pack: "a", wantErr: "cycle"},
+		{pack: "missing", wantErr: "extends ../nowhere"},
+		{pack: "invalid", wantErr: "failed to decode manifest"},
+		{pack: "deep0", wantErr: fmt.Sprintf("more than %d packs", maxExtendsDepth)},
+	}
+
+	for _, tt := range tests {
+		_, _, err := openPackChain(filepath.Join(root, tt.pack), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.pack, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOpenPackChainRelativeCycle(t *testing.T) {
+	root := t.TempDir()
+
+	writeStaged(t, root, map[string]string{
+		"a/manifest.yaml": "version: 1\nextends: ../b\n",
+		"b/manifest.yaml": "version: 1\nextends: ../a\n",
+	})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, _, err := openPackChain("./a", nil); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("error = %v, want a cycle", err)
+	}
+}
@@ -0,0 +1,192 @@
+package ignite
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelim opens and closes the optional header of a file template.
+const frontMatterDelim = "---"
+
+// Post-processors a template header may list.
+const (
+	postGofmt   = "gofmt"
+	postNewline = "newline"
+)
+
+// Ways a template of an extending pack may combine with the template of the
+// same file in the pack it extends, instead of replacing it.
+const (
+	mergeAppend  = "append"
+	mergePrepend = "prepend"
+)
+
+// defaultFileMode is the mode of generated files whose template does not set
+// one.
+const defaultFileMode fs.FileMode = 0o644
+
+// templateHeader is the optional front matter of a file template, a YAML
+// document between two "---" lines at the very start of the template:
+//
+//	---
+//	when: .Features.grpc
+//	mode: "0755"
+//	post: [gofmt]
+//	merge: append
+//	raw: true
+//	---
+//
+// A template whose output starts with a "---" line must begin with an empty
+// header ("---" twice).
+type templateHeader struct {
+	// When is a template pipeline; the file is only generated if it is true
+	// (in the sense of text/template's if).
+	When string `yaml:"when"`
+	// Mode is the octal file mode of the generated file, e.g. "0755".
+	Mode string `yaml:"mode"`
+	// Post lists the post-processors applied to the rendered file, in order.
+	Post []string `yaml:"post"`
+	// Merge is "append" or "prepend" to add the rendered template after or
+	// before the file rendered by the next template for the same path, e.g.
+	// the one of an extended pack, rather than replace it.
+	Merge string `yaml:"merge"`
+	// Raw copies the body into the generated file as is instead of executing
+	// it as a template, e.g. for binary files.
+	Raw bool `yaml:"raw"`
+
+	when *template.Template
+	mode fs.FileMode
+}
+
+// splitFrontMatter separates the header of a template from its body. name
+// identifies the template in errors. Templates without a header get an empty
+// one.
+func splitFrontMatter(name string, content []byte) (templateHeader, []byte, error) {
+	header := templateHeader{mode: defaultFileMode}
+
+	first, rest, ok := cutLine(content)
+	if !ok || strings.TrimRight(string(first), "\r") != frontMatterDelim {
+		return header, content, nil
+	}
+
+	var raw []byte
+
+	for {
+		line, next, ok := cutLine(rest)
+		if !ok && len(line) == 0 {
+			return header, nil, fmt.Errorf("template %s: front matter is not closed with %q", name, frontMatterDelim)
+		}
+
+		if strings.TrimRight(string(line), "\r") == frontMatterDelim {
+			rest = next
+
+			break
+		}
+
+		raw = append(raw, line...)
+		raw = append(raw, '\n')
+		rest = next
+	}
+
+	if len(bytes.TrimSpace(raw)) > 0 {
+		decoder := yaml.NewDecoder(bytes.NewReader(raw))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&header); err != nil {
+			return header, nil, fmt.Errorf("template %s: invalid front matter: %w", name, err)
+		}
+	}
+
+	if err := header.validate(); err != nil {
+		return header, nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	return header, rest, nil
+}
+
+// cutLine returns the first line of b, without its newline, and the rest. ok is
+// false if b has no newline, in which case line is all of b.
+func cutLine(b []byte) (line, rest []byte, ok bool) {
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return b, nil, false
+	}
+
+	return b[:i], b[i+1:], true
+}
+
+func (h *templateHeader) validate() error {
+	if h.When != "" {
+		tmpl, err := template.New("when").Funcs(templateFuncs).Parse("{{ if " + h.When + " }}true{{ end }}")
+		if err != nil {
+			return fmt.Errorf("invalid when %q: %w", h.When, err)
+		}
+
+		h.when = tmpl
+	}
+
+	if h.Mode != "" {
+		mode, err := strconv.ParseUint(h.Mode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return fmt.Errorf("invalid mode %q: must be octal permission bits such as 0755", h.Mode)
+		}
+
+		h.mode = fs.FileMode(mode)
+	}
+
+	for _, post := range h.Post {
+		if post != postGofmt && post != postNewline {
+			return fmt.Errorf("unknown post-processor %q (one of: %s, %s)", post, postGofmt, postNewline)
+		}
+	}
+
+	if h.Merge != "" && h.Merge != mergeAppend && h.Merge != mergePrepend {
+		return fmt.Errorf("unknown merge %q (one of: %s, %s)", h.Merge, mergeAppend, mergePrepend)
+	}
+
+	return nil
+}
+
+// holds reports whether the header's when condition is true for data. Headers
+// without a condition always hold.
+func (h templateHeader) holds(data templateData) (bool, error) {
+	if h.when == nil {
+		return true, nil
+	}
+
+	var buf strings.Builder
+	if err := h.when.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf("failed to evaluate when %q: %v", h.When, err)
+	}
+
+	return buf.String() != "", nil
+}
+
+// postProcess applies the header's post-processors to the rendered content.
+func (h templateHeader) postProcess(content []byte) ([]byte, error) {
+	for _, post := range h.Post {
+		switch post {
+		case postGofmt:
+			formatted, err := format.Source(content)
+			if err != nil {
+				return nil, fmt.Errorf("gofmt: %v", err)
+			}
+
+			content = formatted
+		case postNewline:
+			content = bytes.TrimRight(content, " \t\r\n")
+			if len(content) > 0 {
+				content = append(content, '\n')
+			}
+		}
+	}
+
+	return content, nil
+}
@@ -0,0 +1,140 @@
+package ignite
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantBody string
+		wantMode fs.FileMode
+		wantErr  string
+	}{
+		{name: "no header", template: "body\n", wantBody: "body\n", wantMode: defaultFileMode},
+		{name: "empty header", template: "---\n---\n---\nbody\n", wantBody: "---\nbody\n", wantMode: defaultFileMode},
+		{name: "mode", template: "---\nmode: \"0755\"\n---\n#!/bin/sh\n", wantBody: "#!/bin/sh\n", wantMode: 0o755},
+		{name: "crlf", template: "---\r\nmode: \"0600\"\r\n---\r\nbody", wantBody: "body", wantMode: 0o600},
+		{name: "unclosed", template: "---\nmode: \"0755\"\n", wantErr: "not closed"},
+		{name: "unknown field", template: "---\nowner: root\n---\n", wantErr: "field owner not found"},
+		{name: "invalid mode", template: "---\nmode: \"0999\"\n---\n", wantErr: "invalid mode"},
+		{name: "mode out of range", template: "---\nmode: \"01777\"\n---\n", wantErr: "invalid mode"},
+		{name: "unknown post-processor", template: "---\npost: [prettier]\n---\n", wantErr: "unknown post-processor"},
+		{name: "unknown merge", template: "---\nmerge: replace\n---\n", wantErr: "unknown merge"},
+		{name: "invalid when", template: "---\nwhen: \"{{\"\n---\n", wantErr: "invalid when"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, body, err := splitFrontMatter("file.tmpl", []byte(tt.template))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+
+			if header.mode != tt.wantMode {
+				t.Errorf("mode = %v, want %v", header.mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestTemplateHeaderHolds(t *testing.T) {
+	header, _, err := splitFrontMatter("file.tmpl", []byte("---\nwhen: eq .DBType \"postgres\"\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for dbType, want := range map[string]bool{"postgres": true, "mysql": false, "": false} {
+		got, err := header.holds(templateData{DBType: dbType})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Errorf("holds with DBType %q = %v, want %v", dbType, got, want)
+		}
+	}
+
+	if ok, err := (templateHeader{}).holds(templateData{}); !ok || err != nil {
+		t.Errorf("header without when: holds = %v, %v", ok, err)
+	}
+}
+
+func TestTemplateHeaderPostProcess(t *testing.T) {
+	tests := []struct {
+		post    []string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{post: []string{postGofmt}, content: "package main\nfunc  main( ){}\n", want: "package main\n\nfunc main() {}\n"},
+		{post: []string{postGofmt}, content: "package main\nfunc {", wantErr: true},
+		{post: []string{postNewline}, content: "text\n\n\n  ", want: "text\n"},
+		{post: []string{postNewline}, content: "text", want: "text\n"},
+		{post: []string{postNewline}, content: "\n\n", want: ""},
+		{content: "as is  ", want: "as is  "},
+	}
+
+	for _, tt := range tests {
+		got, err := templateHeader{Post: tt.post}.postProcess([]byte(tt.content))
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v of %q succeeded, want an error", tt.post, tt.content)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v of %q: %v", tt.post, tt.content, err)
+		} else if string(got) != tt.want {
+			t.Errorf("%v of %q = %q, want %q", tt.post, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestFrontMatterInProject(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templates := t.TempDir()
+
+	writeStaged(t, templates, map[string]string{
+		"files/Makefile.tmpl":  "---\nmode: \"0755\"\npost: [newline]\n---\nbuild:\n\n\n",
+		"files/README.md.tmpl": "---\nwhen: eq .DBType \"mysql\"\n---\n# {{ .ProjectName }}\n",
+	})
+
+	result, err := Plan(Options{Module: "api", Database: "postgres", Controller: "http", Templates: templates})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	files := make(map[string]File)
+	for _, f := range result.Files {
+		files[f.Path] = f
+	}
+
+	if makefile := files["Makefile"]; makefile.Mode != 0o755 || string(makefile.Content) != "build:\n" {
+		t.Errorf("Makefile = %q with mode %v, want %q with mode 0755", makefile.Content, makefile.Mode, "build:\n")
+	}
+
+	if _, ok := files["README.md"]; ok {
+		t.Error("README.md generated although its when condition is false")
+	}
+}
@@ -0,0 +1,125 @@
+package ignite
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FS is a file system a project is generated into. It is read like any fs.FS,
+// so the files it already holds can be checked for conflicts, and written with
+// MkdirAll and WriteFile. Names are slash-separated paths relative to its
+// root, as in io/fs.
+//
+// DirFS writes to disk, MemFS keeps the project in memory and NewTarFS and
+// NewZipFS stream it into an archive.
+type FS interface {
+	fs.FS
+	// MkdirAll creates the directory name, along with any missing parents.
+	MkdirAll(name string, perm fs.FileMode) error
+	// WriteFile writes data to the file name, creating it or truncating it.
+	// Its parent directory exists.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// DirFS returns the file system of the directory dir on disk, which need not
+// exist yet. Projects generated into it are staged next to dir and moved into
+// place only once complete.
+func DirFS(dir string) FS {
+	return &dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	fullPath, err := d.join("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(fullPath, perm)
+}
+
+func (d *dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	fullPath, err := d.join("write", name)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fullPath, data, perm)
+}
+
+func (d *dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	return filepath.Join(d.dir, filepath.FromSlash(name)), nil
+}
+
+// subFS is the directory dir of fsys.
+type subFS struct {
+	fsys FS
+	dir  string
+}
+
+// subDir returns the directory dir of fsys, which need not exist yet, as an
+// FS.
+func subDir(fsys FS, dir string) FS {
+	if d, ok := fsys.(*dirFS); ok {
+		return DirFS(filepath.Join(d.dir, filepath.FromSlash(dir)))
+	}
+
+	return &subFS{fsys: fsys, dir: dir}
+}
+
+func (s *subFS) Open(name string) (fs.File, error) {
+	fullName, err := s.join("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fsys.Open(fullName)
+}
+
+func (s *subFS) MkdirAll(name string, perm fs.FileMode) error {
+	fullName, err := s.join("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	return s.fsys.MkdirAll(fullName, perm)
+}
+
+func (s *subFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	fullName, err := s.join("write", name)
+	if err != nil {
+		return err
+	}
+
+	return s.fsys.WriteFile(fullName, data, perm)
+}
+
+func (s *subFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	return path.Join(s.dir, name), nil
+}
+
+// fsName describes fsys in messages: the directory of file systems on disk.
+func fsName(fsys FS) string {
+	switch f := fsys.(type) {
+	case *dirFS:
+		return f.dir
+	case *subFS:
+		return f.dir
+	default:
+		return "the target"
+	}
+}
@@ -0,0 +1,203 @@
+package ignite
+
+import (
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// templateFuncs are the helper functions available to every template.
+//
+//   - lower, upper, title: change the case of a string.
+//   - camel, pascal, snake, kebab: convert identifiers such as "user_id",
+//     "user-id" or "UserID" to userId, UserId, user_id and user-id.
+//   - plural, singular: naive English pluralisation of a word.
+//   - quote, squote: wrap a string in double or single quotes.
+//   - indent n s: indent every line of s by n spaces; nindent also prefixes
+//     a newline.
+//   - default d v: v, or d if v is empty.
+//   - join sep list, replace old new s, trim s, contains sub s, hasPrefix p s
+//     and hasSuffix x s: thin wrappers around the strings package with the
+//     subject last so they can be used in pipelines.
+var templateFuncs = template.FuncMap{
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"title":     titleCase,
+	"camel":     camelCase,
+	"pascal":    pascalCase,
+	"snake":     func(s string) string { return strings.Join(lowerWords(s), "_") },
+	"kebab":     func(s string) string { return strings.Join(lowerWords(s), "-") },
+	"plural":    plural,
+	"singular":  singular,
+	"quote":     strconv.Quote,
+	"squote":    func(s string) string { return "'" + s + "'" },
+	"indent":    indent,
+	"nindent":   func(n int, s string) string { return "\n" + indent(n, s) },
+	"default":   defaultValue,
+	"join":      func(sep string, list []string) string { return strings.Join(list, sep) },
+	"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trim":      strings.TrimSpace,
+	"contains":  func(sub, s string) bool { return strings.Contains(s, sub) },
+	"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+}
+
+// words splits an identifier into its words. Underscores, dashes, dots,
+// slashes and spaces separate words, as do lower-to-upper case changes and
+// the end of an acronym ("HTTPServer" is "HTTP" and "Server").
+func words(s string) []string {
+	var (
+		out     []string
+		current []rune
+	)
+
+	runes := []rune(s)
+
+	flush := func() {
+		if len(current) > 0 {
+			out = append(out, string(current))
+			current = nil
+		}
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+
+			continue
+		}
+
+		if unicode.IsUpper(r) && len(current) > 0 {
+			prev := current[len(current)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				flush()
+			}
+		}
+
+		current = append(current, r)
+	}
+
+	flush()
+
+	return out
+}
+
+func lowerWords(s string) []string {
+	ws := words(s)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+
+	return ws
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return string(runes)
+}
+
+func titleCase(s string) string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		fields[i] = capitalize(f)
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func pascalCase(s string) string {
+	ws := lowerWords(s)
+	for i, w := range ws {
+		ws[i] = capitalize(w)
+	}
+
+	return strings.Join(ws, "")
+}
+
+func camelCase(s string) string {
+	ws := lowerWords(s)
+	for i := 1; i < len(ws); i++ {
+		ws[i] = capitalize(ws[i])
+	}
+
+	return strings.Join(ws, "")
+}
+
+// plural returns the plural of an English word using the common suffix rules.
+func plural(s string) string {
+	lower := strings.ToLower(s)
+
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
+
+// singular reverses plural for the suffixes it produces.
+func singular(s string) string {
+	lower := strings.ToLower(s)
+
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(lower, "ses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss"):
+		return s[:len(s)-1]
+	default:
+		return s
+	}
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// defaultValue returns value unless it is the zero value of its type, in which
+// case def is returned.
+func defaultValue(def, value any) any {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	case bool:
+		if !v {
+			return def
+		}
+	case int:
+		if v == 0 {
+			return def
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,637 @@
+package ignite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Phases of `ignite <project_name>` a hook can run at. Every phase runs in the
+// staging directory, so a failing hook leaves nothing behind.
+const (
+	// hookPreRender runs before the templates are rendered, in the empty
+	// project.
+	hookPreRender = "pre-render"
+	// hookPostRender runs once the rendered files are written.
+	hookPostRender = "post-render"
+	// hookPostModules runs after go mod init and git init.
+	hookPostModules = "post-modules"
+)
+
+var hookPhases = []string{hookPreRender, hookPostRender, hookPostModules}
+
+// Policies for the hooks of template packs, named after the flags selecting
+// them.
+const (
+	// HooksAsk runs the hooks of template packs once approved.
+	HooksAsk = ""
+	// HooksTrust runs them without asking for approval.
+	HooksTrust = "trust-hooks"
+	// HooksSkip runs no hook.
+	HooksSkip = "no-hooks"
+)
+
+// approvedHooksName is the file in the user's ignite configuration directory
+// recording the digest of every hook the user approved, one per line.
+const approvedHooksName = "approved-hooks"
+
+// hookCommandsName is the file in the user's ignite configuration directory
+// listing the commands, one per line, that hooks of template packs may run
+// besides defaultHookCommands.
+const hookCommandsName = "hook-commands"
+
+// defaultHookCommands are the commands any hook may run: an executable,
+// followed by the arguments it must be run with, if any. go is limited to
+// subcommands that do not build or run code from the project, unlike go run
+// or go generate.
+var defaultHookCommands = []string{"git", "go fmt", "go mod tidy", "gofmt"}
+
+// hookMaxSteps bounds the Starlark steps a hook may execute.
+const hookMaxSteps = 100_000_000
+
+// manifestHook declares a hook script of a template pack.
+type manifestHook struct {
+	// Script is the name of the Starlark script in the template source, e.g.
+	// hooks/codeowners.star.
+	Script string `yaml:"script"`
+	Phase  string `yaml:"phase"`
+	// Commands lists the executables the script may run, looked up in PATH.
+	Commands []string `yaml:"commands"`
+}
+
+func (h manifestHook) validate(ts *templateSet) error {
+	if !isSupported(hookPhases, h.Phase) {
+		return fmt.Errorf("unknown phase %q (one of: %s)", h.Phase, strings.Join(hookPhases, ", "))
+	}
+
+	if _, _, err := ts.lookup(h.Script); err != nil {
+		return fmt.Errorf("unknown script %q", h.Script)
+	}
+
+	for _, command := range h.Commands {
+		if command == "" || strings.ContainsAny(command, `/\`) {
+			return fmt.Errorf("invalid command %q: must be the name of an executable in PATH", command)
+		}
+	}
+
+	return nil
+}
+
+// projectHook is a hook script loaded from its template source.
+type projectHook struct {
+	manifestHook
+	source  templateSource
+	content []byte
+	// trusted hooks come from the embedded templates or the user's
+	// configuration directory and never need approval.
+	trusted bool
+	// allowed limits the commands the hook runs, unless it is trusted.
+	allowed hookCommands
+}
+
+// Hook describes a hook script of a template pack submitted for approval.
+type Hook struct {
+	Script string
+	// Source is the template source the script is read from.
+	Source string
+	Phase  string
+	// Commands lists the executables the script may run.
+	Commands []string
+	Content  string
+}
+
+// digest identifies the hook as approved by the user: its script, phase and
+// commands.
+func (h *projectHook) digest() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00", h.Script, h.Phase, strings.Join(h.Commands, ","))
+	hash.Write(h.content)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// loadHooks returns the hooks declared in the project manifest, once approved
+// according to the initializer's hook policy.
+//
+// Hooks of template packs given with --templates must be approved: they are
+// passed, scripts included, to the initializer's approveHooks, unless the same
+// hooks were approved before or --trust-hooks is set. Without approveHooks,
+// e.g. without a terminal to ask on, unapproved hooks are an error. With
+// --no-hooks no hook runs. Whatever the policy, their commands must be allowed
+// by readHookCommands.
+func (p *projectInitializer) loadHooks() ([]*projectHook, error) {
+	manifest, err := loadManifest(p.templates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hooks: %w", err)
+	}
+
+	if len(manifest.Hooks) == 0 {
+		return nil, nil
+	}
+
+	if p.hookPolicy == HooksSkip {
+		fmt.Fprintf(p.out, "Skipping %d template hook(s) (--%s)\n", len(manifest.Hooks), HooksSkip)
+
+		return nil, nil
+	}
+
+	hooks := make([]*projectHook, 0, len(manifest.Hooks))
+
+	for _, declared := range manifest.Hooks {
+		content, source, err := p.templates.lookup(declared.Script)
+		if err != nil {
+			return nil, fmt.Errorf("hook %s: %w", declared.Script, err)
+		}
+
+		hooks = append(hooks, &projectHook{
+			manifestHook: declared,
+			source:       source,
+			content:      content,
+			trusted:      source.label == sourceEmbedded || source.label == sourceUserConfig,
+		})
+	}
+
+	allowed, err := readHookCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range hooks {
+		if hook.trusted {
+			continue
+		}
+
+		for _, command := range hook.Commands {
+			if _, ok := allowed[command]; !ok {
+				commandsPath, _ := hookCommandsPath()
+
+				return nil, fmt.Errorf("hook %s: command %q is not allowed (allowed: %s): list it in %s to let template hooks run it",
+					hook.Script, command, allowed, commandsPath)
+			}
+		}
+
+		hook.allowed = allowed
+	}
+
+	if p.hookPolicy == HooksTrust {
+		return hooks, nil
+	}
+
+	approved, err := readApprovedHooks()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*projectHook
+
+	for _, hook := range hooks {
+		if !hook.trusted && !approved[hook.digest()] {
+			pending = append(pending, hook)
+		}
+	}
+
+	if len(pending) == 0 {
+		return hooks, nil
+	}
+
+	if p.approveHooks == nil {
+		return nil, fmt.Errorf("the template pack has %d hook(s) that need approval: run interactively, or pass --%s to run them or --%s to skip them",
+			len(pending), HooksTrust, HooksSkip)
+	}
+
+	submitted := make([]Hook, 0, len(pending))
+	for _, hook := range pending {
+		submitted = append(submitted, Hook{
+			Script:   hook.Script,
+			Source:   hook.source.label,
+			Phase:    hook.Phase,
+			Commands: hook.Commands,
+			Content:  string(hook.content),
+		})
+	}
+
+	ok, err := p.approveHooks(submitted)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("template hooks were not approved (pass --%s to generate the project without them)", HooksSkip)
+	}
+
+	if err := recordApprovedHooks(pending); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func approvedHooksPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the configuration directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "ignite", approvedHooksName), nil
+}
+
+func hookCommandsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the configuration directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "ignite", hookCommandsName), nil
+}
+
+// hookCommands maps the executables hooks of template packs may run to the
+// leading arguments they may run them with. An empty list of arguments allows
+// any.
+type hookCommands map[string][][]string
+
+func (c hookCommands) add(command string) {
+	fields := strings.Fields(command)
+	if len(fields) > 0 {
+		c[fields[0]] = append(c[fields[0]], fields[1:])
+	}
+}
+
+// allows reports whether argv starts with one of the allowed commands.
+func (c hookCommands) allows(argv []string) bool {
+	for _, args := range c[argv[0]] {
+		if len(argv) > len(args) && slices.Equal(argv[1:len(args)+1], args) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c hookCommands) String() string {
+	var commands []string
+
+	for _, name := range slices.Sorted(maps.Keys(c)) {
+		for _, args := range c[name] {
+			commands = append(commands, strings.Join(append([]string{name}, args...), " "))
+		}
+	}
+
+	return strings.Join(commands, ", ")
+}
+
+// readHookCommands returns the commands hooks of template packs may run:
+// defaultHookCommands and those listed in the user's hook-commands file.
+func readHookCommands() (hookCommands, error) {
+	allowed := make(hookCommands)
+	for _, command := range defaultHookCommands {
+		allowed.add(command)
+	}
+
+	commandsPath, err := hookCommandsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(commandsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return allowed, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", commandsPath, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if command := strings.TrimSpace(scanner.Text()); !strings.HasPrefix(command, "#") {
+			allowed.add(command)
+		}
+	}
+
+	return allowed, nil
+}
+
+// readApprovedHooks returns the digests of the hooks approved so far.
+func readApprovedHooks() (map[string]bool, error) {
+	approved := make(map[string]bool)
+
+	approvedPath, err := approvedHooksPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(approvedPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return approved, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", approvedPath, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if digest := strings.TrimSpace(scanner.Text()); digest != "" {
+			approved[digest] = true
+		}
+	}
+
+	return approved, nil
+}
+
+// recordApprovedHooks adds the digests of hooks to the approved hooks.
+func recordApprovedHooks(hooks []*projectHook) error {
+	approvedPath, err := approvedHooksPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(approvedPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to record approved hooks: %w", err)
+	}
+
+	f, err := os.OpenFile(approvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to record approved hooks: %w", err)
+	}
+	defer f.Close()
+
+	for _, hook := range hooks {
+		if _, err := fmt.Fprintln(f, hook.digest()); err != nil {
+			return fmt.Errorf("failed to record approved hooks: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runHooks runs the hooks of phase, in manifest order, on the project being
+// generated in root.
+func (p *projectInitializer) runHooks(ctx context.Context, hooks []*projectHook, phase, root string) error {
+	for _, hook := range hooks {
+		if hook.Phase != phase {
+			continue
+		}
+
+		log.Printf("Running %s hook %s", phase, hook.Script)
+
+		if err := p.runHook(ctx, hook, root); err != nil {
+			return fmt.Errorf("%s hook %s: %w", phase, hook.Script, err)
+		}
+	}
+
+	return nil
+}
+
+// runHook executes the Starlark script of hook. Scripts have no access to the
+// host beyond these predeclared names:
+//
+//   - phase: the phase the hook runs at.
+//   - answers: a dict of the project's answers: project (the module path),
+//     name, and every template variable, built-ins included.
+//   - read_file(path), write_file(path, content), exists(path): files of the
+//     project, by path relative to its root, which they cannot leave.
+//   - run(command, *args): runs one of the hook's commands in the project
+//     root and returns its standard output. A non-zero exit fails the hook.
+//
+// fail(message) aborts the hook, and with it the generation, and print
+// writes to the initializer's output. load is not available.
+func (p *projectInitializer) runHook(ctx context.Context, hook *projectHook, root string) error {
+	answers := starlark.NewDict(8)
+	answers.SetKey(starlark.String("project"), starlark.String(p.projectName))
+	answers.SetKey(starlark.String("name"), starlark.String(p.directoryName()))
+
+	vars := p.templateData().Vars
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		answers.SetKey(starlark.String(name), starlarkValue(vars[name]))
+	}
+
+	answers.Freeze()
+
+	fsys := hookFS{root: root}
+
+	predeclared := starlark.StringDict{
+		"phase":      starlark.String(hook.Phase),
+		"answers":    answers,
+		"read_file":  starlark.NewBuiltin("read_file", fsys.readFile),
+		"write_file": starlark.NewBuiltin("write_file", fsys.writeFile),
+		"exists":     starlark.NewBuiltin("exists", fsys.exists),
+		"run":        starlark.NewBuiltin("run", hookRunner(ctx, root, hook.Commands, hook.allowed)),
+	}
+
+	thread := &starlark.Thread{
+		Name: hook.Script,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintf(p.out, "[%s] %s\n", hook.Script, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(hookMaxSteps)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel("interrupted")
+		case <-done:
+		}
+	}()
+
+	opts := &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+	_, err := starlark.ExecFileOptions(opts, thread, hook.Script, hook.content, predeclared)
+
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+
+	return err
+}
+
+// starlarkValue converts an answer to a Starlark value.
+func starlarkValue(value any) starlark.Value {
+	switch v := value.(type) {
+	case string:
+		return starlark.String(v)
+	case bool:
+		return starlark.Bool(v)
+	case int:
+		return starlark.MakeInt(v)
+	default:
+		return starlark.String(optionString(value))
+	}
+}
+
+// hookFS gives hooks access to the files below root.
+type hookFS struct {
+	root string
+}
+
+// path returns the location of the project path rel, which must stay inside
+// the project root, symbolic links included.
+func (h hookFS) path(rel string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(rel)) {
+		return "", fmt.Errorf("path %q is outside the project", rel)
+	}
+
+	full := filepath.Join(h.root, filepath.FromSlash(rel))
+
+	root, err := filepath.EvalSymlinks(h.root)
+	if err != nil {
+		return "", err
+	}
+
+	// the deepest existing ancestor must resolve inside the root
+	existing := full
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+				return "", fmt.Errorf("path %q is outside the project", rel)
+			}
+
+			return full, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		existing = filepath.Dir(existing)
+	}
+}
+
+func (h hookFS) readFile(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rel string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &rel); err != nil {
+		return nil, err
+	}
+
+	full, err := h.path(rel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", b.Name(), rel, errors.Unwrap(err))
+	}
+
+	return starlark.String(content), nil
+}
+
+func (h hookFS) writeFile(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rel, content string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &rel, "content", &content); err != nil {
+		return nil, err
+	}
+
+	full, err := h.path(rel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", b.Name(), rel, err)
+	}
+
+	mode := defaultFileMode
+	if info, err := os.Stat(full); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.WriteFile(full, []byte(content), mode); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", b.Name(), rel, errors.Unwrap(err))
+	}
+
+	return starlark.None, nil
+}
+
+func (h hookFS) exists(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rel string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &rel); err != nil {
+		return nil, err
+	}
+
+	full, err := h.path(rel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	_, err = os.Stat(full)
+
+	return starlark.Bool(err == nil), nil
+}
+
+// hookRunner returns the run builtin of a hook allowed to run commands, with
+// the arguments allowed permits unless it is nil.
+func hookRunner(ctx context.Context, root string, commands []string, allowed hookCommands) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%s: unexpected keyword arguments", b.Name())
+		}
+
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s: missing command", b.Name())
+		}
+
+		argv := make([]string, len(args))
+
+		for i, arg := range args {
+			s, ok := starlark.AsString(arg)
+			if !ok {
+				return nil, fmt.Errorf("%s: argument %d is %s, want string", b.Name(), i+1, arg.Type())
+			}
+
+			argv[i] = s
+		}
+
+		if !isSupported(commands, argv[0]) {
+			declared := "none"
+			if len(commands) > 0 {
+				declared = strings.Join(commands, ", ")
+			}
+
+			return nil, fmt.Errorf("%s: command %q is not declared by the hook (declared: %s)", b.Name(), argv[0], declared)
+		}
+
+		if allowed != nil && !allowed.allows(argv) {
+			return nil, fmt.Errorf("%s: %s is not allowed (allowed: %s)", b.Name(), strings.Join(argv, " "), allowed)
+		}
+
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Dir = root
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v: %s", b.Name(), strings.Join(argv, " "), err, strings.TrimSpace(stderr.String()))
+		}
+
+		return starlark.String(stdout.String()), nil
+	}
+}
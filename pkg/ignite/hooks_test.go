@@ -0,0 +1,144 @@
+package ignite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHookFSPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	writeStaged(t, root, map[string]string{"go.mod": "module api\n"})
+
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+
+	tests := []struct {
+		rel     string
+		wantErr bool
+	}{
+		{rel: "go.mod"},
+		{rel: "internal/new/file.go"},
+		{rel: "../outside", wantErr: true},
+		{rel: "internal/../../outside", wantErr: true},
+		{rel: filepath.Join(outside, "file"), wantErr: true},
+		{rel: "escape", wantErr: true},
+		{rel: "escape/new/file", wantErr: true},
+	}
+
+	fsys := hookFS{root: root}
+
+	for _, tt := range tests {
+		_, err := fsys.path(tt.rel)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("path(%q) error = %v, want error %v", tt.rel, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHookCommands(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	writeStaged(t, config, map[string]string{"ignite/" + hookCommandsName: "# tools of the platform team\nmake lint\n\nbuf\n"})
+
+	allowed, err := readHookCommands()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		argv []string
+		want bool
+	}{
+		{argv: []string{"go", "mod", "tidy"}, want: true},
+		{argv: []string{"go", "fmt", "./..."}, want: true},
+		{argv: []string{"go", "run", "."}},
+		{argv: []string{"go", "generate", "./..."}},
+		{argv: []string{"go", "mod"}},
+		{argv: []string{"go"}},
+		{argv: []string{"gofmt", "-w", "."}, want: true},
+		{argv: []string{"make", "lint"}, want: true},
+		{argv: []string{"make", "deploy"}},
+		{argv: []string{"buf", "generate"}, want: true},
+		{argv: []string{"curl", "example.com"}},
+	}
+
+	for _, tt := range tests {
+		if got := allowed.allows(tt.argv); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.argv, got, tt.want)
+		}
+	}
+}
+
+// hookInitializer returns the initializer of a project from a template pack
+// with the hook script, declaring commands, at the post-render phase.
+func hookInitializer(t *testing.T, script string, commands string) *projectInitializer {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templates := t.TempDir()
+
+	writeStaged(t, templates, map[string]string{
+		"manifest.yaml":   "version: 1\nstructure:\n  - path: README.md\n    type: file\nhooks:\n  - script: hooks/hook.star\n    phase: post-render\n    commands: " + commands + "\n",
+		"hooks/hook.star": script,
+	})
+
+	opts := Options{Module: "api", Database: "postgres", Controller: "http", Templates: templates, Hooks: HooksTrust}
+
+	p, err := opts.initializer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestLoadHooksCommandNotAllowed(t *testing.T) {
+	p := hookInitializer(t, "", "[curl]")
+
+	if _, err := p.loadHooks(); err == nil || !strings.Contains(err.Error(), `command "curl" is not allowed`) {
+		t.Errorf("error = %v, want curl not allowed even with --%s", err, HooksTrust)
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string
+	}{
+		{name: "allowed command", script: `run("gofmt", "-l", ".")`},
+		{name: "write inside", script: `write_file("internal/x.txt", "x")`},
+		{name: "go run", script: `run("go", "run", ".")`, wantErr: "go run . is not allowed"},
+		{name: "undeclared command", script: `run("git", "init")`, wantErr: `command "git" is not declared`},
+		{name: "write outside", script: `write_file("../x.txt", "x")`, wantErr: "outside the project"},
+		{name: "read outside", script: `read_file("/etc/passwd")`, wantErr: "outside the project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := hookInitializer(t, tt.script, "[go, gofmt]")
+
+			hooks, err := p.loadHooks()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = p.runHooks(context.Background(), hooks, hookPostRender, t.TempDir())
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("runHooks: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
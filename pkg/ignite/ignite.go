@@ -0,0 +1,280 @@
+// Package ignite generates Go service projects from templates. It is the engine
+// of the ignite command, which is a thin wrapper around it.
+//
+// Generate creates a project into a file system (see FS) and Plan renders it
+// without writing anything. Add, Upgrade and Check work on projects generated
+// before, from the options recorded in their .ignite.yaml. Components other
+// than the built-in ones are added with Register.
+package ignite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Options configures the generation of a project.
+type Options struct {
+	// Module is the module path of the project, e.g. "github.com/acme/api".
+	// The project is named after its last element.
+	Module string
+	// Database and Controller select the database and controller components,
+	// and Workflow and Dockerfile the CI workflow and the Dockerfile. They
+	// are the same as the built-in variables of the same name, which may be
+	// given in Variables instead.
+	Database   string
+	Controller string
+	Workflow   bool
+	Dockerfile bool
+	// Variables holds the raw values of the template variables, as given with
+	// --set name=value, and the answers to the questions of the components
+	// added with With, named <component>.<question>.
+	Variables map[string]string
+	// With names the components provided by plugins to add to the project.
+	With []string
+	// Templates is a template directory or pack (path@ref,
+	// git+file:///repo@ref, module@version) looked up before the user's and
+	// the embedded templates, as with the --templates flag.
+	Templates string
+	// InPlace generates the project at the root of the file system, instead
+	// of into a directory named after the project.
+	InPlace bool
+	// Conflicts is the strategy for files that already exist, one of the
+	// Conflict constants.
+	Conflicts string
+	// Hooks is the policy for the hooks of template packs, one of the Hooks
+	// constants.
+	Hooks string
+	// Prompt, if not nil, asks for every variable and question without a
+	// given value. Otherwise they get their default.
+	Prompt PromptFunc
+	// ApproveHooks is asked to approve the hooks of template packs that were
+	// not approved before. Without it, such hooks are an error.
+	ApproveHooks func(hooks []Hook) (bool, error)
+	// Output receives progress messages and the output of the commands run
+	// in the project (go mod init, go get, git init). Nil discards them.
+	Output io.Writer
+}
+
+// Result describes a generated project.
+type Result struct {
+	// Name is the name of the project, the last element of its module path.
+	Name   string
+	Module string
+	// Dir is the directory of the project in the file system it was
+	// generated into: Name, or "." if generated in place.
+	Dir string
+	// Components lists the selected built-in components, followed by the
+	// components provided by plugins.
+	Components []string
+	// Variables holds the value of every template variable.
+	Variables map[string]any
+	// TemplateVersion identifies the templates the project was rendered from.
+	TemplateVersion string
+	// Files lists the directories and files written, relative to Dir.
+	Files []File
+}
+
+// File is a directory or file of a generated project.
+type File struct {
+	// Path is slash-separated and relative to the project root.
+	Path    string
+	IsDir   bool
+	Mode    fs.FileMode
+	Content []byte
+	// Metadata is set for the files ignite keeps about the project: its
+	// .ignite.yaml and the snapshots under .ignite.
+	Metadata bool
+}
+
+// Generate generates the project described by opts into fsys and returns what
+// it wrote.
+//
+// The templates are rendered, the hooks of template packs run, and the module
+// and git repository are initialized in a staging directory, and the project
+// is only written to fsys once every step has succeeded. Files that already
+// exist in fsys are handled according to opts.Conflicts. If ctx is cancelled,
+// generation stops and nothing is written.
+func Generate(ctx context.Context, opts Options, fsys FS) (*Result, error) {
+	p, err := opts.initializer()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, target := ".", fsys
+	if !opts.InPlace {
+		dir = p.directoryName()
+		target = subDir(fsys, dir)
+	}
+
+	log.Println("Initializing project", p.projectName, "in", fsName(target))
+
+	plan, err := p.runSetup(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.result(dir, plan), nil
+}
+
+// Plan returns the project Generate would write for opts, without writing
+// anything. Hooks do not run and the module and git repository are not
+// initialized.
+func Plan(opts Options) (*Result, error) {
+	p, err := opts.initializer()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := p.planProject()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := p.directoryName()
+	if opts.InPlace {
+		dir = "."
+	}
+
+	return p.result(dir, plan), nil
+}
+
+// WriteTree writes the files of r as a directory tree rooted at the project
+// name. If showContent is true the content of every file is written after the
+// tree.
+func (r *Result) WriteTree(w io.Writer, showContent bool) error {
+	plan := &projectPlan{items: make([]planItem, 0, len(r.Files))}
+
+	for _, f := range r.Files {
+		plan.items = append(plan.items, planItem{path: f.Path, isDir: f.IsDir, content: f.Content, mode: f.Mode})
+	}
+
+	return plan.printTree(w, r.Name, showContent)
+}
+
+// initializer returns the initializer generating the project of opts, with
+// its variables resolved and its components validated.
+func (opts *Options) initializer() (*projectInitializer, error) {
+	if err := ValidateProjectName(opts.Module); err != nil {
+		return nil, err
+	}
+
+	given := maps.Clone(opts.Variables)
+	if given == nil {
+		given = make(map[string]string)
+	}
+
+	options := map[string]string{
+		"database":   opts.Database,
+		"controller": opts.Controller,
+		"workflow":   boolOption(opts.Workflow),
+		"dockerfile": boolOption(opts.Dockerfile),
+	}
+
+	for name, value := range options {
+		if value == "" {
+			continue
+		}
+
+		if _, ok := given[name]; ok {
+			return nil, fmt.Errorf("variable %s is given both as an option and in the variables", name)
+		}
+
+		given[name] = value
+	}
+
+	p := newProjectInitializer("", "", false, false)
+	p.projectName = opts.Module
+	p.conflictStrategy = opts.Conflicts
+	p.hookPolicy = opts.Hooks
+	p.approveHooks = opts.ApproveHooks
+	p.out = outputOf(opts.Output)
+
+	var err error
+
+	p.templates, err = newTemplateSet(opts.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	given, answers := splitComponentAnswers(given)
+
+	if opts.Prompt != nil {
+		err = runInInteractiveMode(p, given, opts.Prompt)
+	} else {
+		err = runFlagMode(p, given)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.enableComponents(opts.With, answers, opts.Prompt); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// result describes the project of the initializer, generated into dir, as
+// written by plan.
+func (p *projectInitializer) result(dir string, plan *projectPlan) *Result {
+	data := p.templateData()
+
+	r := &Result{
+		Name:            p.directoryName(),
+		Module:          p.projectName,
+		Dir:             dir,
+		Variables:       data.Vars,
+		TemplateVersion: p.templates.version(),
+		Files:           make([]File, 0, len(plan.items)),
+	}
+
+	for _, c := range data.Components {
+		r.Components = append(r.Components, c.Name)
+	}
+
+	plugins := make([]string, 0, len(p.plugins))
+	for name := range p.plugins {
+		plugins = append(plugins, name)
+	}
+
+	sort.Strings(plugins)
+	r.Components = append(r.Components, plugins...)
+
+	for _, item := range plan.items {
+		f := File{
+			Path:     item.path,
+			IsDir:    item.isDir,
+			Mode:     item.mode,
+			Content:  item.content,
+			Metadata: item.path == lockFileName || strings.HasPrefix(item.path, baseDir+"/"),
+		}
+
+		switch {
+		case item.isDir:
+			f.Mode = fs.ModeDir | os.ModePerm
+		case f.Mode == 0:
+			f.Mode = defaultFileMode
+		}
+
+		r.Files = append(r.Files, f)
+	}
+
+	return r
+}
+
+// outputOf returns out, or a writer discarding everything if it is nil.
+func outputOf(out io.Writer) io.Writer {
+	if out == nil {
+		return io.Discard
+	}
+
+	return out
+}
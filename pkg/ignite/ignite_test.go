@@ -0,0 +1,157 @@
+package ignite
+
+import (
+	"context"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go mod init, go get and git init")
+	}
+
+	for _, command := range []string{"go", "git"} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("%s is not installed", command)
+		}
+	}
+
+	// keep the user's templates and approved hooks out of the way
+	goConfigHome(t)
+
+	tests := []struct {
+		database   string
+		controller string
+		// module is required in go.mod, server is expected in
+		// cmd/server/main.go.
+		module string
+		server string
+	}{
+		{database: "postgres", controller: "http", module: "github.com/jackc/pgx/v5", server: "http.Server"},
+		{database: "postgres", controller: "grpc", module: "github.com/jackc/pgx/v5", server: "grpc.NewServer"},
+		{database: "mysql", controller: "http", module: "github.com/go-sql-driver/mysql", server: "http.Server"},
+		{database: "mysql", controller: "grpc", module: "github.com/go-sql-driver/mysql", server: "grpc.NewServer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.database+"/"+tt.controller, func(t *testing.T) {
+			fsys := NewMemFS()
+
+			result, err := Generate(context.Background(), Options{
+				Module:     "github.com/acme/api",
+				Database:   tt.database,
+				Controller: tt.controller,
+				Workflow:   true,
+				Dockerfile: true,
+			}, fsys)
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+
+			if result.Dir != "api" {
+				t.Errorf("Dir = %q, want api", result.Dir)
+			}
+
+			for _, component := range []string{tt.database, tt.controller} {
+				if !isSupported(result.Components, component) {
+					t.Errorf("Components = %v, want %s", result.Components, component)
+				}
+			}
+
+			for _, f := range result.Files {
+				if _, err := fs.Stat(fsys, "api/"+f.Path); err != nil {
+					t.Errorf("result file %s was not written: %v", f.Path, err)
+				}
+			}
+
+			wantContent := map[string]string{
+				"go.mod":             tt.module,
+				"cmd/server/main.go": tt.server,
+				".ignite.yaml":       "database: " + tt.database,
+				// go.mod, the Dockerfile and the CI workflow agree on Go
+				"Dockerfile":               "FROM golang:" + goVersion + "-alpine",
+				".github/workflows/ci.yml": `go-version: "` + goVersion + `"`,
+			}
+
+			if content, _ := fs.ReadFile(fsys, "api/go.mod"); !strings.Contains(string(content), "\ngo "+goVersion+".0\n") {
+				t.Errorf("go.mod does not require go %s.0:\n%s", goVersion, content)
+			}
+
+			for name, want := range wantContent {
+				content, err := fs.ReadFile(fsys, "api/"+name)
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+
+					continue
+				}
+
+				if !strings.Contains(string(content), want) {
+					t.Errorf("%s does not contain %q", name, want)
+				}
+			}
+
+			if info, err := fs.Stat(fsys, "api/internal/"+tt.database+"/queries"); err != nil || !info.IsDir() {
+				t.Errorf("internal/%s/queries is not a directory: %v", tt.database, err)
+			}
+
+			if _, err := fs.Stat(fsys, "api/.git"); err != nil {
+				t.Errorf("git repository not initialized: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateUnknownDatabase(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	fsys := NewMemFS()
+
+	_, err := Generate(context.Background(), Options{Module: "api", Database: "oracle", Controller: "http"}, fsys)
+	if err == nil {
+		t.Fatal("Generate succeeded with an unknown database")
+	}
+
+	if entries, _ := fs.ReadDir(fsys, "."); len(entries) > 0 {
+		t.Errorf("Generate wrote %d entries after failing", len(entries))
+	}
+}
+
+func TestGenerateOffline(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go mod init, go get and git init")
+	}
+
+	for _, command := range []string{"go", "git"} {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("%s is not installed", command)
+		}
+	}
+
+	goConfigHome(t)
+
+	// an empty module cache and no proxy to fill it from
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+
+	var out strings.Builder
+
+	fsys := NewMemFS()
+
+	_, err := Generate(context.Background(), Options{Module: "api", Database: "postgres", Controller: "http", Output: &out}, fsys)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	gomod, _ := fs.ReadFile(fsys, "api/go.mod")
+	if !strings.Contains(string(gomod), "github.com/jackc/pgx/v5 v") {
+		t.Errorf("go.mod does not require the database driver:\n%s", gomod)
+	}
+
+	if !strings.Contains(out.String(), "go mod tidy") {
+		t.Errorf("output does not ask to run go mod tidy:\n%s", out.String())
+	}
+}
@@ -0,0 +1,517 @@
+package ignite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type projectInitializer struct {
+	projectName    string
+	dbType         string
+	controlType    string
+	withWorkflow   bool
+	withDockerfile bool
+	// conflictStrategy decides what happens to files that already exist in
+	// the project directory (see conflicts.go).
+	conflictStrategy string
+	// templates resolves the manifest and the templates it refers to.
+	templates *templateSet
+	// vars holds the values of the template variables declared in the
+	// template schema, other than the built-in ones (see schema.go).
+	vars map[string]any
+	// hookPolicy decides whether the hooks of template packs run (see
+	// hooks.go).
+	hookPolicy string
+	// approveHooks is asked to approve the hooks of template packs that were
+	// not approved before (see hooks.go).
+	approveHooks func(hooks []Hook) (bool, error)
+	// plugins maps the plugin components added to the project to the
+	// answers to their questions (see plugins.go).
+	plugins map[string]map[string]any
+	// out receives progress messages and the output of the commands run in
+	// the project.
+	out io.Writer
+}
+
+// goVersion is the Go release generated projects target: the go directive of
+// their go.mod, and the Go toolchain of the Dockerfile and CI workflow. It is
+// the oldest release the pinned modules of the built-in components support.
+const goVersion = "1.24"
+
+// Ports generated services listen on by default.
+const (
+	defaultHTTPPort = 3030
+	defaultGRPCPort = 9090
+)
+
+// templateData is the context every template is rendered with.
+type templateData struct {
+	// ProjectName is the name of the project directory, e.g. "api".
+	ProjectName string
+	// ModulePath is the Go module path, e.g. "github.com/acme/api".
+	ModulePath string
+	// ModuleName is the same as ModulePath, kept for existing templates.
+	ModuleName string
+	GoVersion  string
+	// DBType is "postgres", "mysql" or empty; ControlType is "grpc", "http"
+	// or empty.
+	DBType         string
+	ControlType    string
+	WithWorkflow   bool
+	WithDockerfile bool
+	SqlPackage     bool
+	// Features holds true for every enabled feature: "database", the database
+	// type, "controller", the controller type, "workflow", "dockerfile" and
+	// the name of every plugin component.
+	Features map[string]bool
+	// Components lists the selected components (see components.go), whose
+	// MakeTargets, ReadmeSection and EnvVars render their contributions.
+	Components []templateComponent
+	Ports      templatePorts
+	// Port is the port the server listens on: Ports.GRPC for gRPC projects,
+	// Ports.HTTP otherwise.
+	Port int
+	// Vars holds the value of every template variable, including the built-in
+	// database, controller, workflow and dockerfile.
+	Vars map[string]any
+}
+
+type templatePorts struct {
+	HTTP int
+	GRPC int
+}
+
+// newProjectInitializer returns a new projectInitializer rendering the embedded
+// templates and discarding its output.
+//
+// The parameters are as follows:
+//
+//   - dbType: the database type to be used (e.g. postgres, mysql).
+//   - controlType: the type of controller to be generated (e.g. gRPC, HTTP).
+//   - withWorkflow: if true, a GitHub Actions workflow will be generated.
+//   - withDockerfile: if true, a Dockerfile will be generated.
+func newProjectInitializer(dbType, controlType string, withWorkflow, withDockerfile bool) *projectInitializer {
+	return &projectInitializer{
+		dbType:         dbType,
+		controlType:    controlType,
+		withWorkflow:   withWorkflow,
+		withDockerfile: withDockerfile,
+		templates:      embeddedTemplateSet(),
+		out:            io.Discard,
+	}
+}
+
+// runSetup initializes a new project given the configuration in target and
+// returns the plan it wrote.
+//
+// It does the following steps:
+//
+//   - creates a staging directory and runs the pre-render hooks in it.
+//   - plans the project structure and checks it for conflicts.
+//   - creates the project structure in the staging directory and runs the
+//     post-render hooks.
+//   - initializes the project modules in the staging directory and runs the
+//     post-modules hooks.
+//   - moves the staged project into target.
+//
+// Nothing is written to target until every step has succeeded. If any step
+// fails, or ctx is cancelled (e.g. on Ctrl+C), the staging directory is removed
+// and an error is returned.
+func (p *projectInitializer) runSetup(ctx context.Context, target FS) (*projectPlan, error) {
+	hooks, err := p.loadHooks()
+	if err != nil {
+		return nil, err
+	}
+
+	// an existing module is kept, or replaced with --force
+	existingModule, err := existingModuleFiles(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingModule != nil && p.conflictStrategy == ConflictAbort {
+		return nil, fmt.Errorf("failed to initialize project modules: %s already contains a go.mod file (use --%s to replace it, or --%s or --%s to keep it)",
+			fsName(target), ConflictForce, ConflictSkipExisting, ConflictMerge)
+	}
+
+	keepModule := existingModule != nil && p.conflictStrategy != ConflictForce
+
+	_, err = fs.Stat(target, ".git")
+	withGit := err != nil
+
+	stage, err := newStagingAreaFor(target)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.discard()
+
+	if err := p.runHooks(ctx, hooks, hookPreRender, stage.dir); err != nil {
+		return nil, err
+	}
+
+	plan, err := p.resolvePlan(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project structure: %w", err)
+	}
+
+	if err := createDirectories(plan, DirFS(stage.dir)); err != nil {
+		return nil, fmt.Errorf("failed to create project structure: %w", err)
+	}
+
+	if err := p.runHooks(ctx, hooks, hookPostRender, stage.dir); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("project generation interrupted: %w", err)
+	}
+
+	if keepModule {
+		if err := p.keepModule(stage.dir, existingModule); err != nil {
+			return nil, fmt.Errorf("failed to initialize project modules: %w", err)
+		}
+	}
+
+	if err := p.initializeModules(ctx, stage.dir, !keepModule, withGit); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("project generation interrupted: %w", ctx.Err())
+		}
+
+		return nil, fmt.Errorf("failed to initialize project modules: %w", err)
+	}
+
+	if err := p.runHooks(ctx, hooks, hookPostModules, stage.dir); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("project generation interrupted: %w", err)
+	}
+
+	if err := stage.commit(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// resolvePlan builds the project plan, checks it against the files already
+// present in target and resolves any conflicts with the configured strategy.
+// The project metadata (lock file and base snapshots) is added after conflicts
+// are resolved, so it is always rewritten. Nothing is written to target.
+func (p *projectInitializer) resolvePlan(target fs.FS) (*projectPlan, error) {
+	rendered, err := p.buildPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, err := detectConflicts(rendered, target)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := resolveConflicts(rendered, conflicts, p.conflictStrategy, p.out)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.withLockFile(plan, rendered)
+}
+
+// buildPlan loads the project manifest from the initializer's templates, keeps
+// the entries whose conditions hold for the current configuration and renders
+// them into memory.
+func (p *projectInitializer) buildPlan() (*projectPlan, error) {
+	manifest, err := loadManifest(p.templates)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := buildPlan(p.templates, manifest.entries(p), p.templateData())
+	if err != nil {
+		return nil, err
+	}
+
+	return p.withComponents(plan)
+}
+
+// templateData returns the context the initializer's templates are rendered
+// with.
+func (p *projectInitializer) templateData() templateData {
+	data := templateData{
+		ProjectName:    p.directoryName(),
+		ModulePath:     p.projectName,
+		ModuleName:     p.projectName,
+		GoVersion:      goVersion,
+		DBType:         p.dbType,
+		ControlType:    p.controlType,
+		WithWorkflow:   p.withWorkflow,
+		WithDockerfile: p.withDockerfile,
+		SqlPackage:     p.dbType == "postgres",
+		Features:       make(map[string]bool),
+		Ports:          templatePorts{HTTP: defaultHTTPPort, GRPC: defaultGRPCPort},
+		Port:           defaultHTTPPort,
+		Vars:           p.builtinValues(),
+	}
+
+	for name, value := range p.vars {
+		data.Vars[name] = value
+	}
+
+	if port, ok := p.vars["http_port"].(int); ok {
+		data.Ports.HTTP, data.Port = port, port
+	}
+
+	if port, ok := p.vars["grpc_port"].(int); ok {
+		data.Ports.GRPC = port
+	}
+
+	data.Components = p.templateComponents()
+
+	for _, c := range data.Components {
+		data.Features[c.Option] = true
+		data.Features[c.Name] = true
+	}
+
+	for name := range p.plugins {
+		data.Features[name] = true
+	}
+
+	if p.controlType == "grpc" {
+		data.Port = data.Ports.GRPC
+	}
+
+	// components render their sections with the finished context
+	for i := range data.Components {
+		data.Components[i].data = data
+	}
+
+	return data
+}
+
+// planProject returns the project that runSetup would generate, metadata
+// included, without writing anything. Hooks and module initialization (go mod
+// init, git init) are skipped.
+func (p *projectInitializer) planProject() (*projectPlan, error) {
+	plan, err := p.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	plan, err = p.withLockFile(plan, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	return plan, nil
+}
+
+// initializeModules initializes the project's Go module and Git repository.
+//
+// It runs the following commands in dir:
+//
+//   - go mod init <project_name> and go mod edit -go=<goVersion>, unless
+//     initModule is false because dir already holds the go.mod to use
+//   - go get with the Go modules of the selected components, if any
+//   - git init, unless withGit is false
+//
+// If any of the commands fail, it returns an error. If the modules need a
+// newer Go than goVersion, e.g. those of a plugin, a warning is printed since
+// the Dockerfile and CI workflow no longer match go.mod.
+func (p *projectInitializer) initializeModules(ctx context.Context, dir string, initModule, withGit bool) error {
+	if initModule {
+		log.Println("Initializing go module...")
+
+		err := runCommand(ctx, dir, p.out, "go", "mod", "init", p.projectName)
+		if err != nil {
+			return fmt.Errorf("failed to run go mod init: %w", err)
+		}
+
+		// go mod init writes the version of the local toolchain
+		err = runCommand(ctx, dir, p.out, "go", "mod", "edit", "-go="+goVersion+".0")
+		if err != nil {
+			return fmt.Errorf("failed to run go mod edit: %w", err)
+		}
+	}
+
+	if err := addGoModules(ctx, dir, p.goModules(), p.out); err != nil {
+		return err
+	}
+
+	if version := moduleGoVersion(dir); initModule && version != goVersion+".0" {
+		fmt.Fprintf(p.out, "Warning: go.mod requires go %s, but the Dockerfile and CI workflow use Go %s: update them\n", version, goVersion)
+	}
+
+	if !withGit {
+		log.Println("Project path is already a git repository, skipping git init")
+
+		return nil
+	}
+
+	log.Println("Initializing git repository...")
+
+	err := runCommand(ctx, dir, p.out, "git", "init")
+	if err != nil {
+		return fmt.Errorf("failed to run git init: %w", err)
+	}
+
+	return nil
+}
+
+// moduleGoVersion returns the go directive of the go.mod file in dir, empty
+// if it has none.
+func moduleGoVersion(dir string) string {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "go "); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+
+	return ""
+}
+
+// moduleFiles are the files of a Go module kept from an existing project.
+var moduleFiles = []string{"go.mod", "go.sum"}
+
+// existingModuleFiles returns the content of the go.mod and go.sum files of
+// target, by name, or nil if it has no go.mod.
+func existingModuleFiles(target fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for _, name := range moduleFiles {
+		content, err := fs.ReadFile(target, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		files[name] = content
+	}
+
+	if _, ok := files["go.mod"]; !ok {
+		return nil, nil
+	}
+
+	return files, nil
+}
+
+// keepModule writes the files of the existing module to dir, in place of the
+// one go mod init would create, so that the Go modules of the components are
+// added to it. A module path other than the project's is reported to out, as
+// the generated imports would not resolve.
+func (p *projectInitializer) keepModule(dir string, files map[string][]byte) error {
+	fmt.Fprintln(p.out, "Keeping the existing go.mod")
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, defaultFileMode); err != nil {
+			return err
+		}
+	}
+
+	for _, line := range strings.Split(string(files["go.mod"]), "\n") {
+		module, ok := strings.CutPrefix(strings.TrimSpace(line), "module ")
+		if !ok {
+			continue
+		}
+
+		if module = strings.Trim(strings.TrimSpace(module), `"`); module != p.projectName {
+			fmt.Fprintf(p.out, "Warning: the existing go.mod declares module %s, not %s\n", module, p.projectName)
+		}
+
+		break
+	}
+
+	return nil
+}
+
+// createDirectories writes the directories and files of the given plan to
+// fsys.
+//
+// Parent directories of files are created as needed and files get the mode
+// their template declares (see templateHeader). The function logs
+// information about the directories and files it creates, and returns an error
+// if any step of the process fails.
+func createDirectories(plan *projectPlan, fsys FS) error {
+	for _, item := range plan.items {
+		if item.isDir {
+			log.Println("Creating directory:", item.path)
+
+			if err := fsys.MkdirAll(item.path, os.ModePerm); err != nil {
+				return fmt.Errorf("failed to create directory %s: %v", item.path, err)
+			}
+
+			continue
+		}
+
+		log.Println("Creating file:", item.path)
+
+		if err := fsys.MkdirAll(path.Dir(item.path), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create directory %s: %v", path.Dir(item.path), err)
+		}
+
+		mode := item.mode
+		if mode == 0 {
+			mode = defaultFileMode
+		}
+
+		if err := fsys.WriteFile(item.path, item.content, mode); err != nil {
+			return fmt.Errorf("failed to create file %s: %v", item.path, err)
+		}
+	}
+
+	return nil
+}
+
+// directoryName returns the name of the directory the project is created in,
+// which is the last element of the module path (e.g. "api" for
+// "github.com/acme/api").
+func (p *projectInitializer) directoryName() string {
+	return path.Base(p.projectName)
+}
+
+// ValidateProjectName checks that name can be used both as a Go module path
+// and, through its last element, as a directory name.
+func ValidateProjectName(name string) error {
+	if strings.TrimSpace(name) != name || name == "" {
+		return fmt.Errorf("invalid project name %q: must not be empty or contain surrounding spaces", name)
+	}
+
+	if strings.Contains(name, `\`) || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("invalid project name %q: must be a module path such as my_project or github.com/acme/my_project", name)
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("invalid project name %q: module path elements must not be empty, \".\" or \"..\"", name)
+		}
+	}
+
+	return nil
+}
+
+// runCommand runs a command with the given name and arguments in dir, and
+// returns an error if the command fails. The command is killed if ctx is
+// cancelled. Its stdout and stderr are written to out.
+func runCommand(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stderr = out
+	cmd.Stdout = out
+
+	return cmd.Run()
+}
@@ -0,0 +1,250 @@
+package ignite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lockFileName is the file written to the project root describing how the
+// project was generated.
+const lockFileName = ".ignite.yaml"
+
+// baseDir holds a copy of every file as last rendered from the templates,
+// relative to the project root. It is the common ancestor `ignite upgrade` uses
+// to merge template changes with the user's edits.
+const baseDir = ".ignite/base"
+
+// version is the ignite release, set at build time with -ldflags
+// "-X github.com/EmilioCliff/ignite/pkg/ignite.version=v1.2.3". When empty the
+// module version from the build info is used instead.
+var version string
+
+type lockFile struct {
+	IgniteVersion   string `yaml:"ignite_version"`
+	TemplateVersion string `yaml:"template_version"`
+	// Templates records the template directory or pack (git repository or Go
+	// module) the project was generated from, if any.
+	Templates   *lockTemplates `yaml:"templates,omitempty"`
+	GeneratedAt time.Time      `yaml:"generated_at"`
+	Project     lockProject    `yaml:"project"`
+	Options     lockOptions    `yaml:"options"`
+	// Variables holds the values of the template variables other than the
+	// built-in options.
+	Variables map[string]any `yaml:"variables,omitempty"`
+	// Components maps the plugin components added to the project to the
+	// answers to their questions.
+	Components map[string]map[string]any `yaml:"components,omitempty"`
+	// Files maps every generated file, relative to the project root, to the
+	// checksum of the content ignite wrote.
+	Files map[string]string `yaml:"files"`
+}
+
+type lockTemplates struct {
+	Source string `yaml:"source"`
+	Ref    string `yaml:"ref,omitempty"`
+	// Commit is set for git packs, Version and Sum for module packs, and only
+	// Sum, the checksum of its content, for a template directory.
+	Commit  string `yaml:"commit,omitempty"`
+	Version string `yaml:"version,omitempty"`
+	Sum     string `yaml:"sum,omitempty"`
+	// Extends records the git and module packs the pack extends, from its
+	// parent to the base pack.
+	Extends []lockExtends `yaml:"extends,omitempty"`
+}
+
+// lockExtends records a pack of an extends chain at the revision it resolved
+// to.
+type lockExtends struct {
+	// Extends is the extends value naming the pack in the manifest of the pack
+	// extending it.
+	Extends string `yaml:"extends"`
+	Commit  string `yaml:"commit,omitempty"`
+	Version string `yaml:"version,omitempty"`
+	Sum     string `yaml:"sum,omitempty"`
+}
+
+type lockProject struct {
+	Name   string `yaml:"name"`
+	Module string `yaml:"module"`
+}
+
+type lockOptions struct {
+	Database   string `yaml:"database"`
+	Controller string `yaml:"controller"`
+	Workflow   bool   `yaml:"workflow"`
+	Dockerfile bool   `yaml:"dockerfile"`
+}
+
+// Version returns the version of ignite.
+func Version() string {
+	if version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "(devel)"
+}
+
+// newLockFile describes the project generated from plan with the initializer's
+// options.
+func (p *projectInitializer) newLockFile(plan *projectPlan) *lockFile {
+	lock := &lockFile{
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Project: lockProject{
+			Name:   p.directoryName(),
+			Module: p.projectName,
+		},
+		Files: make(map[string]string),
+	}
+
+	lock.recordTemplates(p.templates)
+	lock.record(p, plan)
+
+	return lock
+}
+
+// recordTemplates updates the ignite and template versions of the lock file to
+// the running ignite and the templates in ts.
+func (lock *lockFile) recordTemplates(ts *templateSet) {
+	lock.IgniteVersion = Version()
+	lock.TemplateVersion = ts.version()
+	lock.Templates = nil
+
+	if ts.pack != nil {
+		lock.Templates = &lockTemplates{
+			Source:  ts.pack.source,
+			Ref:     ts.pack.ref,
+			Commit:  ts.pack.commit,
+			Version: ts.pack.version,
+			Sum:     ts.pack.sum,
+		}
+
+		for _, parent := range ts.pack.extends {
+			lock.Templates.Extends = append(lock.Templates.Extends, lockExtends{
+				Extends: parent.declared,
+				Commit:  parent.commit,
+				Version: parent.version,
+				Sum:     parent.sum,
+			})
+		}
+	}
+}
+
+// record updates the lock file with the initializer's options and the checksums
+// of the files in plan. Files already in the lock file but not in plan are kept.
+func (lock *lockFile) record(p *projectInitializer, plan *projectPlan) {
+	lock.Options = lockOptions{
+		Database:   p.dbType,
+		Controller: p.controlType,
+		Workflow:   p.withWorkflow,
+		Dockerfile: p.withDockerfile,
+	}
+	lock.Variables = p.vars
+	lock.Components = p.plugins
+
+	if lock.Files == nil {
+		lock.Files = make(map[string]string)
+	}
+
+	for _, item := range plan.items {
+		if !item.isDir {
+			lock.Files[item.path] = checksum(item.content)
+		}
+	}
+}
+
+// initializer returns a projectInitializer configured with the options
+// recorded in the lock file.
+func (lock *lockFile) initializer() *projectInitializer {
+	p := newProjectInitializer(
+		lock.Options.Database,
+		lock.Options.Controller,
+		lock.Options.Workflow,
+		lock.Options.Dockerfile,
+	)
+
+	p.projectName = lock.Project.Module
+	p.vars = lock.Variables
+	p.plugins = lock.Components
+
+	return p
+}
+
+// withLockFile returns plan with the project metadata appended: the lock file
+// describing plan and the base snapshot of the files in rendered.
+func (p *projectInitializer) withLockFile(plan, rendered *projectPlan) (*projectPlan, error) {
+	return appendMetadata(plan, p.newLockFile(plan), rendered)
+}
+
+// appendMetadata returns plan with a base snapshot of every file in rendered
+// and lock, as the project's lock file, appended.
+func appendMetadata(plan *projectPlan, lock *lockFile, rendered *projectPlan) (*projectPlan, error) {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(lock); err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", lockFileName, err)
+	}
+
+	items := append([]planItem(nil), plan.items...)
+
+	for _, item := range rendered.items {
+		if !item.isDir {
+			items = append(items, planItem{path: path.Join(baseDir, item.path), content: item.content})
+		}
+	}
+
+	items = append(items, planItem{path: lockFileName, content: buf.Bytes()})
+
+	return &projectPlan{items: items}, nil
+}
+
+// readBase returns the base snapshot of the file at rel in the project in dir,
+// or nil if there is none.
+func readBase(dir, rel string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(baseDir), filepath.FromSlash(rel)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return content, err
+}
+
+// readLockFile reads the lock file of the project in dir.
+func readLockFile(dir string) (*lockFile, error) {
+	content, err := os.ReadFile(filepath.Join(dir, lockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s is not an ignite project: %s not found", dir, lockFileName)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lockFileName, err)
+	}
+
+	var lock lockFile
+	if err := yaml.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", lockFileName, err)
+	}
+
+	return &lock, nil
+}
+
+// checksum returns the sha256 checksum of content in the form "sha256:<hex>".
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
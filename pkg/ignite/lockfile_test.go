@@ -0,0 +1,120 @@
+package ignite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlannedProject writes the project planned for opts to a temporary
+// directory, lock file and base snapshots included, and returns the directory.
+// The module and git repository are not initialized.
+func writePlannedProject(t *testing.T, opts Options) string {
+	t.Helper()
+
+	// keep the user's templates out of the way
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	result, err := Plan(opts)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	for _, f := range result.Files {
+		full := filepath.Join(dir, filepath.FromSlash(f.Path))
+
+		if f.IsDir {
+			err = os.MkdirAll(full, 0o755)
+		} else if err = os.MkdirAll(filepath.Dir(full), 0o755); err == nil {
+			err = os.WriteFile(full, f.Content, f.Mode.Perm())
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLockFile(t *testing.T) {
+	dir := writePlannedProject(t, Options{
+		Module:     "github.com/acme/api",
+		Database:   "mysql",
+		Controller: "grpc",
+		Dockerfile: true,
+	})
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock.Project != (lockProject{Name: "api", Module: "github.com/acme/api"}) {
+		t.Errorf("project = %+v", lock.Project)
+	}
+
+	want := lockOptions{Database: "mysql", Controller: "grpc", Dockerfile: true}
+	if lock.Options != want {
+		t.Errorf("options = %+v, want %+v", lock.Options, want)
+	}
+
+	if lock.IgniteVersion == "" || lock.TemplateVersion == "" || lock.GeneratedAt.IsZero() {
+		t.Errorf("versions or generation time missing: %+v", lock)
+	}
+
+	if len(lock.Files) == 0 {
+		t.Fatal("no file recorded")
+	}
+
+	for name, sum := range lock.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("recorded file %s: %v", name, err)
+
+			continue
+		}
+
+		if checksum(content) != sum {
+			t.Errorf("%s: recorded checksum %s, want %s", name, sum, checksum(content))
+		}
+
+		base, err := readBase(dir, name)
+		if err != nil || base == nil {
+			t.Errorf("%s: no base snapshot (%v)", name, err)
+		} else if string(base) != string(content) {
+			t.Errorf("%s: base snapshot differs from the generated file", name)
+		}
+
+		if strings.HasPrefix(name, baseDir+"/") || name == lockFileName {
+			t.Errorf("metadata file %s recorded as a project file", name)
+		}
+	}
+}
+
+func TestReadLockFileMissing(t *testing.T) {
+	if _, err := readLockFile(t.TempDir()); err == nil {
+		t.Error("readLockFile succeeded without a lock file")
+	}
+}
+
+func TestLockFileInitializer(t *testing.T) {
+	lock := &lockFile{
+		Project:   lockProject{Name: "api", Module: "github.com/acme/api"},
+		Options:   lockOptions{Database: "postgres", Controller: "http", Workflow: true},
+		Variables: map[string]any{"team": "payments"},
+	}
+
+	p := lock.initializer()
+
+	if p.projectName != "github.com/acme/api" || p.dbType != "postgres" || p.controlType != "http" || !p.withWorkflow || p.withDockerfile {
+		t.Errorf("initializer does not have the recorded options: %+v", p)
+	}
+
+	if p.vars["team"] != "payments" {
+		t.Errorf("variables = %v", p.vars)
+	}
+}
@@ -0,0 +1,430 @@
+package ignite
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	manifestName    = "manifest.yaml"
+	manifestVersion = 1
+
+	entryTypeDir  = "dir"
+	entryTypeFile = "file"
+)
+
+// manifestOptions are the option names a manifest condition may refer to
+// besides the variables of the template schema.
+var manifestOptions = builtinVariables
+
+type projectManifest struct {
+	Version int `yaml:"version"`
+	// Extends is the template pack this one builds on (see openPackChain). Its
+	// manifest is the next one in the template set, which this manifest is
+	// applied to (see extend).
+	Extends string `yaml:"extends"`
+	// Remove lists the paths of the extended manifest's entries to drop,
+	// together with the entries below them.
+	Remove    []string           `yaml:"remove"`
+	Fallbacks []manifestFallback `yaml:"fallbacks"`
+	Structure []manifestEntry    `yaml:"structure"`
+	// Hooks are the scripts run while a project is generated (see hooks.go).
+	Hooks []manifestHook `yaml:"hooks"`
+
+	// removed accumulates the paths removed by the manifests applied to the
+	// root one, so the entries of components are removed as well.
+	removed []string
+	// components holds the entries of the files and directories of the
+	// components (see componentEntries), set when the manifest is loaded.
+	components []manifestEntry
+}
+
+// manifestFallback names the template used for files matching a glob pattern
+// that have no template of their own.
+type manifestFallback struct {
+	Pattern  string `yaml:"pattern"`
+	Template string `yaml:"template"`
+}
+
+type manifestEntry struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+	// Template is the project path whose template the file uses, if not its
+	// own.
+	Template string   `yaml:"template"`
+	When     []string `yaml:"when"`
+
+	// template is the project path of the template the file resolves to,
+	// set when the manifest is validated.
+	template string
+	// component is the name of the component the entry belongs to, if it was
+	// not declared by the manifest.
+	component string
+}
+
+// loadManifest reads the project manifest from the template set, applying it to
+// the manifests it extends, validates it against the templates and variables
+// available in the set, and adds the entries of the components.
+func loadManifest(ts *templateSet) (*projectManifest, error) {
+	m, source, err := readManifest(ts, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := loadSchema(ts)
+	if err != nil {
+		return nil, err
+	}
+
+	options := append([]string{}, manifestOptions...)
+	for _, name := range schema.names() {
+		if !isSupported(options, name) {
+			options = append(options, name)
+		}
+	}
+
+	if err := m.validate(ts, options); err != nil {
+		return nil, fmt.Errorf("%s manifest: %w", source.label, err)
+	}
+
+	m.components, err = componentEntries(m, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// readManifest decodes the first manifest of the template set found from the
+// source at index start on. If it extends another pack, it is applied to the
+// next manifest in the set, read the same way.
+func readManifest(ts *templateSet, start int) (*projectManifest, templateSource, error) {
+	content, index, err := ts.lookupFrom(manifestName, start)
+	if err != nil {
+		return nil, templateSource{}, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	source := ts.sources[index]
+
+	m, err := decodeManifest(content)
+	if err != nil {
+		return nil, source, fmt.Errorf("%s manifest: %w", source.label, err)
+	}
+
+	if m.Extends == "" {
+		if len(m.Remove) > 0 {
+			return nil, source, fmt.Errorf("%s manifest: remove needs extends", source.label)
+		}
+
+		return m, source, nil
+	}
+
+	base, _, err := readManifest(ts, index+1)
+	if err != nil {
+		return nil, source, fmt.Errorf("%s manifest extends %s: %w", source.label, m.Extends, err)
+	}
+
+	merged, err := m.extend(base)
+	if err != nil {
+		return nil, source, fmt.Errorf("%s manifest: %w", source.label, err)
+	}
+
+	return merged, source, nil
+}
+
+// decodeManifest decodes a manifest, rejecting unknown fields. It is validated
+// once applied to the manifests it extends: unsupported versions, invalid or
+// duplicated paths, unknown entry types, unknown templates and malformed
+// conditions are all rejected with an error naming the offending entry.
+func decodeManifest(content []byte) (*projectManifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var m projectManifest
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return &m, nil
+}
+
+// extend applies the manifest m to base, the manifest of the pack it extends:
+//
+//   - the entries listed in remove, and the entries below them, are dropped
+//     from base, as are those of the components. Every path listed must
+//     match an entry of base or of a component.
+//   - entries of m with the path of an entry of base replace it in place;
+//     the others are added after those of base.
+//   - the fallbacks of m come before those of base, so they win.
+//   - the hooks of m run after those of base.
+func (m *projectManifest) extend(base *projectManifest) (*projectManifest, error) {
+	if base.Version != manifestVersion {
+		return nil, fmt.Errorf("extended manifest has unsupported version %d (expected %d)", base.Version, manifestVersion)
+	}
+
+	merged := &projectManifest{
+		Version:   m.Version,
+		Fallbacks: append(append([]manifestFallback{}, m.Fallbacks...), base.Fallbacks...),
+		Hooks:     append(append([]manifestHook{}, base.Hooks...), m.Hooks...),
+		removed:   append(append([]string{}, base.removed...), m.Remove...),
+	}
+
+	removed := make(map[string]bool, len(m.Remove))
+
+	for _, entry := range base.Structure {
+		drop := false
+
+		for _, p := range m.Remove {
+			if entry.Path == p || strings.HasPrefix(entry.Path, p+"/") {
+				drop, removed[p] = true, true
+			}
+		}
+
+		if !drop {
+			merged.Structure = append(merged.Structure, entry)
+		}
+	}
+
+	for _, p := range m.Remove {
+		if !removed[p] && !registry.declares(p) {
+			return nil, fmt.Errorf("remove %q matches no entry of the extended manifest", p)
+		}
+	}
+
+	for _, entry := range m.Structure {
+		replaced := false
+
+		for i := range merged.Structure {
+			if merged.Structure[i].Path == entry.Path {
+				merged.Structure[i], replaced = entry, true
+
+				break
+			}
+		}
+
+		if !replaced {
+			merged.Structure = append(merged.Structure, entry)
+		}
+	}
+
+	return merged, nil
+}
+
+func (m *projectManifest) validate(ts *templateSet, options []string) error {
+	if m.Version != manifestVersion {
+		return fmt.Errorf("unsupported manifest version %d (expected %d)", m.Version, manifestVersion)
+	}
+
+	if len(m.Structure) == 0 {
+		return fmt.Errorf("manifest has no structure entries")
+	}
+
+	for i, fallback := range m.Fallbacks {
+		if _, err := path.Match(fallback.Pattern, ""); err != nil || fallback.Pattern == "" {
+			return fmt.Errorf("manifest fallback #%d: invalid pattern %q", i+1, fallback.Pattern)
+		}
+
+		if !ts.exists(fallback.Template) {
+			return fmt.Errorf("manifest fallback #%d (%s): unknown template %q", i+1, fallback.Pattern, fallback.Template)
+		}
+	}
+
+	for i, hook := range m.Hooks {
+		if err := hook.validate(ts); err != nil {
+			return fmt.Errorf("manifest hook #%d (%s): %w", i+1, hook.Script, err)
+		}
+	}
+
+	seen := make(map[string]int, len(m.Structure))
+
+	for i := range m.Structure {
+		entry := &m.Structure[i]
+
+		if err := entry.validate(ts, options); err != nil {
+			return fmt.Errorf("manifest entry #%d (%s): %w", i+1, entry.Path, err)
+		}
+
+		if entry.Type == entryTypeFile {
+			entry.template = m.templateFor(*entry, ts)
+			if entry.template == "" {
+				return fmt.Errorf("manifest entry #%d (%s): no template found (looked for %s/%s%s, %s/%s and the fallbacks)",
+					i+1, entry.Path, filesDir, entry.Path, templateSuffix, filesDir, entry.Path)
+			}
+		}
+
+		if first, ok := seen[entry.Path]; ok {
+			return fmt.Errorf("manifest entry #%d (%s): duplicate path, first declared in entry #%d", i+1, entry.Path, first)
+		}
+
+		seen[entry.Path] = i + 1
+	}
+
+	return nil
+}
+
+func (e manifestEntry) validate(ts *templateSet, options []string) error {
+	if e.Path == "" {
+		return fmt.Errorf("missing path")
+	}
+
+	if path.IsAbs(e.Path) || path.Clean(e.Path) != e.Path || e.Path == "." || strings.HasPrefix(e.Path, "../") {
+		return fmt.Errorf("path must be a clean relative path inside the project")
+	}
+
+	if isTemplatedPath(e.Path) {
+		if _, err := parsePath(e.Path); err != nil {
+			return fmt.Errorf("invalid path template: %w", err)
+		}
+	}
+
+	switch e.Type {
+	case entryTypeDir:
+		if e.Template != "" {
+			return fmt.Errorf("directories cannot have a template")
+		}
+	case entryTypeFile:
+		if e.Template != "" && !ts.exists(e.Template) {
+			return fmt.Errorf("unknown template %q", e.Template)
+		}
+	case "":
+		return fmt.Errorf("missing type (one of: %s, %s)", entryTypeDir, entryTypeFile)
+	default:
+		return fmt.Errorf("unknown type %q (one of: %s, %s)", e.Type, entryTypeDir, entryTypeFile)
+	}
+
+	for _, cond := range e.When {
+		if _, _, err := parseCondition(cond, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// templateFor returns the project path of the template used for the file entry
+// e: its explicit template, its own path, or the template of the first fallback
+// whose pattern matches its path. It returns an empty string if there is none.
+func (m *projectManifest) templateFor(e manifestEntry, ts *templateSet) string {
+	if e.Template != "" {
+		return e.Template
+	}
+
+	if ts.exists(e.Path) {
+		return e.Path
+	}
+
+	for _, fallback := range m.Fallbacks {
+		if ok, _ := path.Match(fallback.Pattern, e.Path); ok {
+			return fallback.Template
+		}
+	}
+
+	return ""
+}
+
+// entries returns the manifest entries whose conditions hold for the given
+// initializer, in declaration order, followed by those of the selected
+// components.
+func (m *projectManifest) entries(p *projectInitializer) []manifestEntry {
+	options := p.manifestOptionValues()
+
+	var selected []manifestEntry
+
+	for _, entry := range append(m.Structure[:len(m.Structure):len(m.Structure)], m.components...) {
+		if entry.matches(options) {
+			selected = append(selected, entry)
+		}
+	}
+
+	return selected
+}
+
+// removes reports whether the manifests applied to the root one remove p.
+func (m *projectManifest) removes(p string) bool {
+	for _, removed := range m.removed {
+		if p == removed || strings.HasPrefix(p, removed+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e manifestEntry) matches(options map[string]string) bool {
+	return conditionsHold(e.When, options)
+}
+
+// conditionsHold reports whether every condition holds for the given option
+// values, in which unset options are empty.
+func conditionsHold(conds []string, options map[string]string) bool {
+	for _, cond := range conds {
+		name, value, _ := splitCondition(cond)
+
+		if value == "" && options[name] == "" {
+			return false
+		}
+
+		if value != "" && options[name] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitCondition splits a condition of the form `option` or `option=value`.
+func splitCondition(cond string) (name, value string, hasValue bool) {
+	name, value, hasValue = strings.Cut(cond, "=")
+
+	return strings.TrimSpace(name), strings.TrimSpace(value), hasValue
+}
+
+// parseCondition splits a condition of the form `option` or `option=value` and
+// checks that it refers to one of the given options.
+func parseCondition(cond string, options []string) (string, string, error) {
+	name, value, hasValue := splitCondition(cond)
+
+	if name == "" {
+		return "", "", fmt.Errorf("invalid condition %q: missing option name", cond)
+	}
+
+	if !isSupported(options, name) {
+		return "", "", fmt.Errorf("invalid condition %q: unknown option %q (one of: %s)", cond, name, strings.Join(options, ", "))
+	}
+
+	if hasValue && value == "" {
+		return "", "", fmt.Errorf("invalid condition %q: missing value", cond)
+	}
+
+	return name, value, nil
+}
+
+// manifestOptionValues maps the initializer's configuration and template
+// variables to the option names used in manifest conditions. Unset options map
+// to an empty string.
+func (p *projectInitializer) manifestOptionValues() map[string]string {
+	options := map[string]string{
+		"database":   p.dbType,
+		"controller": p.controlType,
+		"workflow":   boolOption(p.withWorkflow),
+		"dockerfile": boolOption(p.withDockerfile),
+	}
+
+	for name, value := range p.vars {
+		options[name] = optionString(value)
+	}
+
+	return options
+}
+
+func boolOption(b bool) string {
+	if b {
+		return "true"
+	}
+
+	return ""
+}
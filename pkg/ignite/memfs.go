@@ -0,0 +1,210 @@
+package ignite
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// MemFS is an FS held in memory, e.g. to preview a project or to generate one
+// in tests without touching the disk. The zero value is an empty file system
+// ready to use, and it is safe for concurrent use.
+type MemFS struct {
+	mu sync.RWMutex
+	// entries holds the directories and files by name; the root is implicit.
+	entries map[string]*memEntry
+}
+
+type memEntry struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory file system.
+func NewMemFS() *MemFS {
+	return &MemFS{}
+}
+
+// Open opens the file or directory name. Directories can be read with
+// fs.ReadDir and fs.WalkDir.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if name == "." {
+		return &memDir{info: memInfo{name: ".", mode: fs.ModeDir | 0o777}, entries: m.children(name)}, nil
+	}
+
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	info := memInfo{name: path.Base(name), size: int64(len(e.data)), mode: e.mode, modTime: e.modTime}
+
+	if e.mode.IsDir() {
+		return &memDir{info: info, entries: m.children(name)}, nil
+	}
+
+	return &memFile{info: info, Reader: bytes.NewReader(e.data)}, nil
+}
+
+// MkdirAll creates the directory name, along with any missing parents.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries == nil {
+		m.entries = make(map[string]*memEntry)
+	}
+
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		e, ok := m.entries[dir]
+		if !ok {
+			continue
+		}
+
+		if !e.mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: dir, Err: syscall.ENOTDIR}
+		}
+	}
+
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.entries[dir]; !ok {
+			m.entries[dir] = &memEntry{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+		}
+	}
+
+	return nil
+}
+
+// WriteFile writes data to the file name, creating it or truncating it. Its
+// parent directory must exist.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if parent := path.Dir(name); parent != "." {
+		e, ok := m.entries[parent]
+
+		switch {
+		case !ok:
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+		case !e.mode.IsDir():
+			return &fs.PathError{Op: "write", Path: name, Err: syscall.ENOTDIR}
+		}
+	}
+
+	if e, ok := m.entries[name]; ok && e.mode.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: syscall.EISDIR}
+	}
+
+	if m.entries == nil {
+		m.entries = make(map[string]*memEntry)
+	}
+
+	m.entries[name] = &memEntry{data: bytes.Clone(data), mode: perm.Perm(), modTime: time.Now()}
+
+	return nil
+}
+
+// children returns the entries of the directory dir, sorted by name. The
+// caller holds the lock.
+func (m *MemFS) children(dir string) []fs.DirEntry {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+
+	var entries []fs.DirEntry
+
+	for name, e := range m.entries {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || strings.Contains(rest, "/") {
+			continue
+		}
+
+		entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: rest, size: int64(len(e.data)), mode: e.mode, modTime: e.modTime}))
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries
+}
+
+// memInfo describes a file or directory of a MemFS.
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memFile is an open file of a MemFS. Writes to the file system after it was
+// opened are not seen through it.
+type memFile struct {
+	info memInfo
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory of a MemFS, listing its entries as they were
+// when it was opened.
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: syscall.EISDIR}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+
+	return entries, nil
+}
@@ -0,0 +1,164 @@
+package ignite
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Conflict markers Upgrade writes around the regions changed both in the
+// project and in the templates.
+const (
+	ConflictMarkerCurrent  = "<<<<<<< current"
+	ConflictMarkerSplit    = "======="
+	ConflictMarkerTemplate = ">>>>>>> template"
+)
+
+// mergeThreeWay merges the changes from base to current (the user's edits) with
+// the changes from base to next (the template's changes), line by line.
+//
+// Regions changed on only one side take that side's version; regions changed
+// identically on both sides are kept once. Regions changed differently on both
+// sides are written with both versions between conflict markers, and the second
+// return value reports whether any such region exists.
+func mergeThreeWay(base, current, next []byte) ([]byte, bool) {
+	b, o, t := splitLines(base), splitLines(current), splitLines(next)
+
+	// matchOurs[i] and matchTheirs[i] hold the index of the line matching
+	// base[i] in current and next, or -1.
+	matchOurs := matchLines(b, o)
+	matchTheirs := matchLines(b, t)
+
+	var out []string
+
+	conflicted := false
+	i, j, k := 0, 0, 0
+
+	for i < len(b) || j < len(o) || k < len(t) {
+		if i < len(b) && matchOurs[i] == j && matchTheirs[i] == k {
+			out = append(out, b[i])
+			i, j, k = i+1, j+1, k+1
+
+			continue
+		}
+
+		// find the next base line kept by both sides; everything before it
+		// is an unstable chunk
+		next := i
+		for next < len(b) && (matchOurs[next] < 0 || matchTheirs[next] < 0) {
+			next++
+		}
+
+		endOurs, endTheirs := len(o), len(t)
+		if next < len(b) {
+			endOurs, endTheirs = matchOurs[next], matchTheirs[next]
+		}
+
+		baseChunk, oursChunk, theirsChunk := b[i:next], o[j:endOurs], t[k:endTheirs]
+
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			out = append(out, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			out = append(out, oursChunk...)
+		default:
+			conflicted = true
+
+			out = append(out, ConflictMarkerCurrent+"\n")
+			out = append(out, terminated(oursChunk)...)
+			out = append(out, ConflictMarkerSplit+"\n")
+			out = append(out, terminated(theirsChunk)...)
+			out = append(out, ConflictMarkerTemplate+"\n")
+		}
+
+		i, j, k = next, endOurs, endTheirs
+	}
+
+	return []byte(strings.Join(out, "")), conflicted
+}
+
+// splitLines splits content into lines, each keeping its trailing newline.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// terminated returns lines with a newline added to the last one if it lacks
+// one, so a conflict marker following it starts on its own line.
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+
+	out := append([]string(nil), lines...)
+	out[len(out)-1] += "\n"
+
+	return out
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLines computes a longest common subsequence of a and b and returns, for
+// every line of a, the index of the line of b it is matched with, or -1.
+func matchLines(a, b []string) []int {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			match[i] = j
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return match
+}
+
+// hasConflictMarkers reports whether content still contains unresolved
+// conflict markers written by mergeThreeWay.
+func hasConflictMarkers(content []byte) bool {
+	return bytes.Contains(content, []byte(ConflictMarkerCurrent+"\n")) &&
+		bytes.Contains(content, []byte(ConflictMarkerTemplate+"\n"))
+}
@@ -0,0 +1,103 @@
+package ignite
+
+import "testing"
+
+func TestMergeThreeWay(t *testing.T) {
+	tests := []struct {
+		name                string
+		base, current, next string
+		want                string
+		conflicted          bool
+	}{
+		{
+			name:    "unchanged",
+			base:    "a\nb\nc\n",
+			current: "a\nb\nc\n",
+			next:    "a\nb\nc\n",
+			want:    "a\nb\nc\n",
+		},
+		{
+			name:    "user change only",
+			base:    "a\nb\nc\n",
+			current: "a\nB\nc\n",
+			next:    "a\nb\nc\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "template change only",
+			base:    "a\nb\nc\n",
+			current: "a\nb\nc\n",
+			next:    "a\nb\nC\n",
+			want:    "a\nb\nC\n",
+		},
+		{
+			name:    "changes in separate regions",
+			base:    "a\nb\nc\nd\ne\n",
+			current: "A\nb\nc\nd\ne\n",
+			next:    "a\nb\nc\nd\nE\n",
+			want:    "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:    "same change on both sides",
+			base:    "a\nb\nc\n",
+			current: "a\nx\nc\n",
+			next:    "a\nx\nc\n",
+			want:    "a\nx\nc\n",
+		},
+		{
+			name:    "lines added on both sides",
+			base:    "a\nc\n",
+			current: "a\nb\nc\n",
+			next:    "a\nc\nd\n",
+			want:    "a\nb\nc\nd\n",
+		},
+		{
+			name:    "line deleted by the template",
+			base:    "a\nb\nc\n",
+			current: "a\nb\nc\n",
+			next:    "a\nc\n",
+			want:    "a\nc\n",
+		},
+		{
+			name:       "conflicting changes",
+			base:       "a\nb\nc\n",
+			current:    "a\nours\nc\n",
+			next:       "a\ntheirs\nc\n",
+			want:       "a\n<<<<<<< current\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			conflicted: true,
+		},
+		{
+			name:       "conflict without trailing newline",
+			base:       "a\nb",
+			current:    "a\nours",
+			next:       "a\ntheirs",
+			want:       "a\n<<<<<<< current\nours\n=======\ntheirs\n>>>>>>> template\n",
+			conflicted: true,
+		},
+		{
+			name:    "empty base",
+			base:    "",
+			current: "",
+			next:    "a\n",
+			want:    "a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicted := mergeThreeWay([]byte(tt.base), []byte(tt.current), []byte(tt.next))
+
+			if string(got) != tt.want {
+				t.Errorf("merged content = %q, want %q", got, tt.want)
+			}
+
+			if conflicted != tt.conflicted {
+				t.Errorf("conflicted = %v, want %v", conflicted, tt.conflicted)
+			}
+
+			if hasConflictMarkers(got) != tt.conflicted {
+				t.Errorf("hasConflictMarkers = %v, want %v", !tt.conflicted, tt.conflicted)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package ignite
+
+import (
+	"log"
+)
+
+// runInInteractiveMode asks for every variable declared in the template schema
+// (database type, controller type, workflow, Dockerfile and any variable a
+// template pack adds) with ask, skipping the ones already given and those
+// whose conditions do not hold. Answers are validated against the schema
+// before they are accepted.
+func runInInteractiveMode(data *projectInitializer, given map[string]string, ask PromptFunc) error {
+	log.Println("Running in interactive mode.")
+
+	schema, err := loadSchema(data.templates)
+	if err != nil {
+		return err
+	}
+
+	values, err := data.resolveVariables(schema, given, ask)
+	if err != nil {
+		return err
+	}
+
+	data.applyVariables(values)
+
+	return data.validateComponents()
+}
+
+// runFlagMode validates the inputs provided by the user in flag mode, given
+// with the built-in flags and --set, against the template schema and applies
+// them. Variables that are not given get their default.
+func runFlagMode(data *projectInitializer, given map[string]string) error {
+	schema, err := loadSchema(data.templates)
+	if err != nil {
+		return err
+	}
+
+	values, err := data.resolveVariables(schema, given, nil)
+	if err != nil {
+		return err
+	}
+
+	data.applyVariables(values)
+
+	return data.validateComponents()
+}
+
+func isSupported(supportedList []string, item string) bool {
+	for _, supported := range supportedList {
+		if supported == item {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,233 @@
+package ignite
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packSumsName is the file in the user's ignite configuration directory that
+// records the checksum of every module pack version used, in go.sum format. A
+// version whose checksum changes is rejected.
+const packSumsName = "packs.sum"
+
+// isModulePath reports whether value looks like a Go module path: slash
+// separated, with a dot in its first element, like example.com/blueprints/api.
+func isModulePath(value string) bool {
+	if value == "" || strings.HasPrefix(value, ".") || strings.HasPrefix(value, "/") || strings.ContainsAny(value, `\ :`) {
+		return false
+	}
+
+	first, _, _ := strings.Cut(value, "/")
+
+	return strings.Contains(first, ".")
+}
+
+// moduleDownload is the output of go mod download -json.
+type moduleDownload struct {
+	Path    string
+	Version string
+	Dir     string
+	Sum     string
+	Error   string
+}
+
+// openModulePack downloads the Go module ref through the go command, so the
+// module cache, GOPROXY (including file:// proxies), GOFLAGS, GONOSUMDB and
+// GOSUMDB apply as for any other module, and serves its templates.
+//
+// The module's content is verified against the checksum the go command
+// reports, which the go command itself checks against the checksum database,
+// and against the checksum recorded for the same version in packs.sum. The
+// templates are read from the module's templates directory, the one a pack
+// embeds with //go:embed, or from its root if it has none.
+func openModulePack(ref packRef) (templateSource, *templatePack, error) {
+	log.Println("Downloading template pack", ref.source+"@"+ref.ref)
+
+	download, err := downloadModule(ref.source + "@" + ref.ref)
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("template pack %s@%s: %w", ref.source, ref.ref, err)
+	}
+
+	sum, err := hashModuleDir(download.Dir, download.Path+"@"+download.Version)
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("template pack %s@%s: failed to hash module: %w", download.Path, download.Version, err)
+	}
+
+	if sum != download.Sum {
+		return templateSource{}, nil, fmt.Errorf("template pack %s@%s: checksum mismatch: module cache has %s, expected %s",
+			download.Path, download.Version, sum, download.Sum)
+	}
+
+	if err := verifyPackSum(download.Path, download.Version, sum); err != nil {
+		return templateSource{}, nil, err
+	}
+
+	dir := download.Dir
+	if info, err := os.Stat(filepath.Join(dir, "templates")); err == nil && info.IsDir() {
+		dir = filepath.Join(dir, "templates")
+	}
+
+	pack := &templatePack{
+		kind:    packModule,
+		source:  download.Path,
+		ref:     ref.ref,
+		version: download.Version,
+		sum:     sum,
+	}
+
+	return dirTemplateSource(sourceFlag, dir), pack, nil
+}
+
+// downloadModule runs go mod download for the module query modVersion, e.g.
+// example.com/blueprints/api@v1.4.0, outside of any module.
+func downloadModule(modVersion string) (*moduleDownload, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("go", "mod", "download", "-json", modVersion)
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOWORK=off")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+
+	var download moduleDownload
+	if err := json.Unmarshal(stdout.Bytes(), &download); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("go mod download failed: %v: %s", runErr, strings.TrimSpace(stderr.String()))
+		}
+
+		return nil, fmt.Errorf("failed to decode go mod download output: %w", err)
+	}
+
+	if download.Error != "" {
+		return nil, errors.New(download.Error)
+	}
+
+	if runErr != nil {
+		return nil, fmt.Errorf("go mod download failed: %v: %s", runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	if download.Dir == "" || download.Sum == "" {
+		return nil, fmt.Errorf("go mod download did not report the module directory and checksum")
+	}
+
+	return &download, nil
+}
+
+// hashModuleDir computes the go.sum "h1:" checksum of the module extracted in
+// dir, whose files are named prefix/<path> in the module zip. It matches the
+// go command's dirhash.HashDir with Hash1. .git directories, which module
+// zips never contain, are left out, so that template directories in a working
+// tree can be hashed too.
+func hashModuleDir(dir, prefix string) (string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(files)
+
+	summary := sha256.New()
+
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, f)
+		f.Close()
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(summary, "%x  %s/%s\n", fileHash.Sum(nil), prefix, file)
+	}
+
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// verifyPackSum checks sum against the checksum recorded for the module
+// version in packs.sum, recording it if the version is new.
+func verifyPackSum(modPath, version, sum string) error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate the configuration directory: %w", err)
+	}
+
+	sumsPath := filepath.Join(configDir, "ignite", packSumsName)
+
+	content, err := os.ReadFile(sumsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", sumsPath, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != modPath || fields[1] != version {
+			continue
+		}
+
+		if fields[2] != sum {
+			return fmt.Errorf("template pack %s@%s: checksum mismatch: downloaded %s, %s has %s", modPath, version, sum, sumsPath, fields[2])
+		}
+
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sumsPath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+
+	f, err := os.OpenFile(sumsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s %s %s\n", modPath, version, sum); err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package ignite
+
+import (
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// moduleProxy serves the module versions given, each a map of file names to
+// contents, from a file:// GOPROXY in a temporary directory that the go
+// command uses for the rest of the test.
+func moduleProxy(t *testing.T, modPath string, versions map[string]map[string]string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	proxy := t.TempDir()
+	dir := filepath.Join(proxy, modPath, "@v")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var list []string
+
+	for version, files := range versions {
+		list = append(list, version)
+		gomod := "module " + modPath + "\n"
+
+		zipFile, err := os.Create(filepath.Join(dir, version+".zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		zw := zip.NewWriter(zipFile)
+
+		for name, content := range mergeFiles(files, map[string]string{"go.mod": gomod}) {
+			w, err := zw.Create(modPath + "@" + version + "/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := w.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		zipFile.Close()
+
+		writeStaged(t, dir, map[string]string{
+			version + ".info": `{"Version":"` + version + `","Time":"2024-01-01T00:00:00Z"}`,
+			version + ".mod":  gomod,
+		})
+	}
+
+	writeStaged(t, dir, map[string]string{"list": strings.Join(list, "\n") + "\n"})
+
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+}
+
+// goConfigHome points XDG_CONFIG_HOME at a new temporary directory with go
+// telemetry off, so that the go command run by the test does not keep writing
+// to it after the test.
+func goConfigHome(t *testing.T) {
+	t.Helper()
+
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	writeStaged(t, config, map[string]string{"go/telemetry/mode": "off"})
+}
+
+func mergeFiles(files, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(files)+len(extra))
+
+	for name, content := range extra {
+		merged[name] = content
+	}
+
+	for name, content := range files {
+		merged[name] = content
+	}
+
+	return merged
+}
+
+func TestIsModulePath(t *testing.T) {
+	tests := map[string]bool{
+		"example.com/blueprints/api": true,
+		"github.com/acme/templates":  true,
+		"blueprints/api":             false,
+		"./blueprints":               false,
+		"../blueprints.v2":           false,
+		"/srv/example.com/api":       false,
+		`C:\blueprints.d`:            false,
+		"example.com/my templates":   false,
+		"":                           false,
+	}
+
+	for value, want := range tests {
+		if got := isModulePath(value); got != want {
+			t.Errorf("isModulePath(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestModulePack(t *testing.T) {
+	goConfigHome(t)
+
+	moduleProxy(t, "example.com/blueprints", map[string]map[string]string{
+		"v1.0.0": {"templates/files/README.md.tmpl": "v1\n"},
+		"v1.1.0": {"files/README.md.tmpl": "v1.1\n"},
+	})
+
+	tests := []struct {
+		value   string
+		version string
+		content string
+	}{
+		// the templates directory of a pack that embeds them
+		{value: "example.com/blueprints@v1.0.0", version: "v1.0.0", content: "v1\n"},
+		// the module root of one that does not
+		{value: "example.com/blueprints", version: "v1.1.0", content: "v1.1\n"},
+	}
+
+	for _, tt := range tests {
+		source, pack, err := openTemplatePack(tt.value)
+		if err != nil {
+			t.Fatalf("openTemplatePack(%q): %v", tt.value, err)
+		}
+
+		if pack.kind != packModule || pack.version != tt.version || !strings.HasPrefix(pack.sum, "h1:") {
+			t.Errorf("%s: pack = %+v, want module version %s and its checksum", tt.value, pack, tt.version)
+		}
+
+		content, err := source.read("files/README.md.tmpl")
+		if err != nil || string(content) != tt.content {
+			t.Errorf("%s: template = %q, %v, want %q", tt.value, content, err, tt.content)
+		}
+	}
+
+	if _, _, err := openTemplatePack("example.com/blueprints@v2.0.0"); err == nil {
+		t.Error("opened a module pack version the proxy does not have")
+	}
+}
+
+func TestVerifyPackSum(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+
+	if err := verifyPackSum("example.com/blueprints", "v1.0.0", "h1:first"); err != nil {
+		t.Fatalf("recording a new version: %v", err)
+	}
+
+	if err := verifyPackSum("example.com/blueprints", "v1.1.0", "h1:second"); err != nil {
+		t.Fatalf("recording another version: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(config, "ignite", packSumsName))
+	if want := "example.com/blueprints v1.0.0 h1:first\nexample.com/blueprints v1.1.0 h1:second\n"; string(content) != want {
+		t.Errorf("%s = %q, want %q", packSumsName, content, want)
+	}
+
+	if err := verifyPackSum("example.com/blueprints", "v1.0.0", "h1:first"); err != nil {
+		t.Errorf("verifying a recorded version: %v", err)
+	}
+
+	if err := verifyPackSum("example.com/blueprints", "v1.0.0", "h1:changed"); err == nil {
+		t.Error("accepted a version whose checksum changed")
+	}
+}
+
+func TestModulePackSumChanged(t *testing.T) {
+	goConfigHome(t)
+
+	moduleProxy(t, "example.com/blueprints", map[string]map[string]string{
+		"v1.0.0": {"files/README.md.tmpl": "v1\n"},
+	})
+
+	if err := verifyPackSum("example.com/blueprints", "v1.0.0", "h1:republished"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := openTemplatePack("example.com/blueprints@v1.0.0"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("error = %v, want a checksum mismatch", err)
+	}
+}
@@ -0,0 +1,480 @@
+package ignite
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitPackScheme prefixes references to template packs kept in a local git
+// repository, e.g. git+file:///srv/blueprints.git@v2.1.
+const gitPackScheme = "git+file://"
+
+// Kinds of --templates values.
+const (
+	packDir    = "dir"
+	packGit    = "git"
+	packModule = "module"
+)
+
+// templatePack identifies a template pack resolved to an exact revision: a
+// commit of a git repository or a version of a Go module. A template directory
+// has no revision and is identified by the checksum of its content.
+type templatePack struct {
+	kind string
+	// source is the pack as given, without the ref: a git+file URL, the path
+	// of a working tree or a module path.
+	source string
+	// ref is the requested branch, tag, commit or module version, commit or
+	// version what it resolved to.
+	ref     string
+	commit  string
+	version string
+	// sum is the go.sum-style checksum (h1:...) of a module pack or template
+	// directory.
+	sum string
+	// extends lists the git and module packs of the chain the pack extends,
+	// from its parent to the base pack.
+	extends []*templatePack
+	// declared is the extends value a pack of the chain was opened from.
+	declared string
+}
+
+// packRef is a parsed --templates value.
+type packRef struct {
+	kind   string
+	source string
+	// location is the directory of a template directory or git repository, or
+	// the path of a module.
+	location string
+	ref      string
+}
+
+// parsePackRef interprets the value of --templates. It is either a template
+// directory, used as is, a reference to a git repository at a ref, or a Go
+// module at a version:
+//
+//   - git+file:///path/to/repo[@ref]
+//   - /path/to/working/tree@ref
+//   - example.com/blueprints/api[@version]
+//
+// Git refs default to HEAD and module versions to latest. An existing
+// directory always wins, so a path without "@" is a template directory.
+func parsePackRef(value string) (packRef, error) {
+	if strings.HasPrefix(value, gitPackScheme) {
+		source, ref := splitRef(value)
+
+		u, err := url.Parse(strings.TrimPrefix(source, "git+"))
+		if err != nil || u.Path == "" || (u.Host != "" && u.Host != "localhost") {
+			return packRef{}, fmt.Errorf("invalid template pack %q: must be %s/path/to/repo[@ref]", value, gitPackScheme)
+		}
+
+		if ref == "" {
+			ref = "HEAD"
+		}
+
+		return packRef{kind: packGit, source: source, location: filepath.FromSlash(u.Path), ref: ref}, nil
+	}
+
+	if info, err := os.Stat(value); err == nil && info.IsDir() {
+		return packRef{kind: packDir, source: value, location: value}, nil
+	}
+
+	source, ref := splitRef(value)
+
+	if _, err := os.Stat(source); err == nil {
+		if ref == "" || !isGitRepo(source) {
+			return packRef{}, fmt.Errorf("invalid template pack %q: %s is not a directory or git repository", value, source)
+		}
+
+		return packRef{kind: packGit, source: source, location: source, ref: ref}, nil
+	}
+
+	if isModulePath(source) {
+		if ref == "" {
+			ref = "latest"
+		}
+
+		return packRef{kind: packModule, source: source, location: source, ref: ref}, nil
+	}
+
+	if ref == "" {
+		return packRef{kind: packDir, source: value, location: value}, nil
+	}
+
+	return packRef{}, fmt.Errorf("invalid template pack %q: %s is not a git repository or Go module path", value, source)
+}
+
+// splitRef splits value at an "@" following its last slash.
+func splitRef(value string) (string, string) {
+	i := strings.LastIndex(value, "@")
+	if i < 0 || i < strings.LastIndex(value, "/") {
+		return value, ""
+	}
+
+	return value[:i], value[i+1:]
+}
+
+func isGitRepo(dir string) bool {
+	_, err := gitOutput(dir, "rev-parse", "--git-dir")
+
+	return err == nil
+}
+
+// openTemplatePack returns the template source for value, the --templates
+// flag (see parsePackRef), and the pack it resolved to. The pack of a plain
+// directory is its absolute path and the checksum of its content.
+//
+// Git references are resolved to a commit whose tree is checked out into the
+// ignite cache on first use. Modules are downloaded through the go command
+// (see openModulePack).
+func openTemplatePack(value string) (templateSource, *templatePack, error) {
+	ref, err := parsePackRef(value)
+	if err != nil {
+		return templateSource{}, nil, err
+	}
+
+	switch ref.kind {
+	case packModule:
+		return openModulePack(ref)
+	case packGit:
+		return openGitPack(ref)
+	}
+
+	info, err := os.Stat(ref.location)
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("failed to open templates directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return templateSource{}, nil, fmt.Errorf("templates directory %s is not a directory", ref.location)
+	}
+
+	dir, err := filepath.Abs(ref.location)
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("failed to open templates directory: %w", err)
+	}
+
+	sum, err := hashModuleDir(dir, "templates")
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("failed to read templates directory %s: %w", dir, err)
+	}
+
+	return dirTemplateSource(sourceFlag, ref.location), &templatePack{kind: packDir, source: dir, sum: sum}, nil
+}
+
+// maxExtendsDepth bounds the number of packs a chain of extends may go through.
+const maxExtendsDepth = 8
+
+// openPackChain opens the template pack value, like openTemplatePack, and the
+// packs it extends, following the extends field of each pack's manifest. It
+// returns their sources from the most derived pack to the base one, and the
+// pack value resolved to, with the git and module packs of the chain.
+//
+// A relative directory in extends is relative to the directory of the pack
+// extending it; git and module references are used as they are, at the commit
+// or module version pins maps them to, if any.
+func openPackChain(value string, pins map[string]string) ([]templateSource, *templatePack, error) {
+	source, pack, err := openTemplatePack(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := []templateSource{source}
+	seen := map[string]bool{source.dir: true}
+
+	for current := source; ; {
+		parent, err := packExtends(current)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if parent == "" {
+			return sources, pack, nil
+		}
+
+		if len(sources) > maxExtendsDepth {
+			return nil, nil, fmt.Errorf("template pack %s: more than %d packs extending each other", value, maxExtendsDepth)
+		}
+
+		ref := parent
+		if location, _ := splitRef(parent); !strings.HasPrefix(parent, gitPackScheme) && !filepath.IsAbs(location) && !isModulePath(location) {
+			ref = filepath.Join(current.dir, parent)
+		}
+
+		if pin := pins[parent]; pin != "" {
+			location, _ := splitRef(ref)
+			ref = location + "@" + pin
+		}
+
+		next, nextPack, err := openTemplatePack(ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("template pack %s extends %s: %w", current.dir, parent, err)
+		}
+
+		if pack != nil && nextPack != nil {
+			nextPack.declared = parent
+			pack.extends = append(pack.extends, nextPack)
+		}
+
+		if seen[next.dir] {
+			return nil, nil, fmt.Errorf("template packs extend each other in a cycle: %s extends %s", current.dir, parent)
+		}
+
+		next.label = "extends " + parent
+		seen[next.dir] = true
+		sources = append(sources, next)
+		current = next
+	}
+}
+
+// packExtends returns the extends field of the manifest of the pack in source,
+// empty if it has no manifest.
+func packExtends(source templateSource) (string, error) {
+	content, err := source.read(manifestName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read %s manifest: %w", source.label, err)
+	}
+
+	var m struct {
+		Extends string `yaml:"extends"`
+	}
+
+	if err := yaml.Unmarshal(content, &m); err != nil {
+		return "", fmt.Errorf("%s manifest: failed to decode manifest: %w", source.label, err)
+	}
+
+	return m.Extends, nil
+}
+
+// openGitPack checks out the git template pack ref into the cache.
+func openGitPack(ref packRef) (templateSource, *templatePack, error) {
+	commit, err := gitOutput(ref.location, "rev-parse", "--verify", "--quiet", ref.ref+"^{commit}")
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("template pack %s: unknown ref %q", ref.source, ref.ref)
+	}
+
+	dir, err := checkoutPack(ref.location, commit)
+	if err != nil {
+		return templateSource{}, nil, fmt.Errorf("template pack %s@%s: %w", ref.source, ref.ref, err)
+	}
+
+	pack := &templatePack{kind: packGit, source: ref.source, ref: ref.ref, commit: commit}
+
+	return dirTemplateSource(sourceFlag, dir), pack, nil
+}
+
+// packCacheDir returns the directory template packs are checked out into, one
+// subdirectory per commit.
+func packCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the cache directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, "ignite", "packs"), nil
+}
+
+// checkoutPack extracts the tree of commit in repo into the pack cache and
+// returns its directory. Commits already in the cache are reused.
+func checkoutPack(repo, commit string) (string, error) {
+	cacheDir, err := packCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(cacheDir, commit)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		log.Println("Using cached template pack", dir)
+
+		return dir, nil
+	}
+
+	log.Println("Checking out template pack", commit, "from", repo)
+
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp(cacheDir, ".checkout-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", "-C", repo, "archive", "--format=tar", commit)
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to run git archive: %w", err)
+	}
+
+	extractErr := extractTar(stdout, tmp)
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("git archive failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	if extractErr != nil {
+		return "", extractErr
+	}
+
+	// another ignite may have checked out the same commit in the meantime
+	if err := os.Rename(tmp, dir); err != nil {
+		if info, statErr := os.Stat(dir); statErr == nil && info.IsDir() {
+			return dir, nil
+		}
+
+		return "", fmt.Errorf("failed to populate cache: %w", err)
+	}
+
+	return dir, nil
+}
+
+// extractTar writes the directories and regular files of the tar stream r
+// below dir. Other entries, such as symbolic links, are ignored.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read template pack: %w", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("template pack contains an invalid path %q", header.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+		}
+	}
+}
+
+// gitOutput runs git in dir and returns its trimmed standard output.
+func gitOutput(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// projectTemplateSet returns the templates for the existing ignite project in
+// dir: those of templates, the value of the --templates flag, if it is not
+// empty, or else the template pack recorded in the project's lock file. The
+// recorded pack is used at the commit or module version the project was
+// generated from, whose checksum must still match, if pinned is true, and at
+// the recorded ref (which may have moved since) otherwise. Either way, the
+// packs it extends are used at their recorded commits or versions as long as
+// it still extends them. Template directories have no revision: they are used
+// as they are, and their content must still match its checksum if pinned is
+// true.
+func projectTemplateSet(templates, dir string, pinned bool) (*templateSet, error) {
+	if templates != "" {
+		return newTemplateSet(templates)
+	}
+
+	lock, err := readLockFile(dir)
+	if err != nil || lock.Templates == nil {
+		return newTemplateSet("")
+	}
+
+	recorded := lock.Templates
+
+	// template directories are used as they are: they have no ref
+	value := recorded.Source
+	if recorded.Commit != "" || recorded.Version != "" {
+		ref := recorded.Ref
+		if pinned {
+			ref = recorded.Commit + recorded.Version
+		}
+
+		value += "@" + ref
+	}
+
+	pins := make(map[string]string, len(recorded.Extends))
+	for _, parent := range recorded.Extends {
+		pins[parent.Extends] = parent.Commit + parent.Version
+	}
+
+	ts, err := newPinnedTemplateSet(value, pins)
+	if err != nil {
+		return nil, fmt.Errorf("templates recorded in %s: %w", lockFileName, err)
+	}
+
+	if pinned && recorded.Sum != "" && ts.pack != nil && ts.pack.sum != recorded.Sum {
+		return nil, fmt.Errorf("template pack %s: checksum %s does not match %s recorded in %s (pass --templates to use the templates as they are)",
+			value, ts.pack.sum, recorded.Sum, lockFileName)
+	}
+
+	if ts.pack == nil {
+		return ts, nil
+	}
+
+	for _, parent := range recorded.Extends {
+		for _, opened := range ts.pack.extends {
+			if (pinned || parent.Commit != "" || parent.Version != "") && opened.declared == parent.Extends && parent.Sum != "" && opened.sum != parent.Sum {
+				return nil, fmt.Errorf("template pack %s extends %s: checksum %s does not match %s recorded in %s (pass --templates to use the templates as they are)",
+					value, parent.Extends, opened.sum, parent.Sum, lockFileName)
+			}
+		}
+	}
+
+	return ts, nil
+}
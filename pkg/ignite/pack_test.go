@@ -0,0 +1,230 @@
+package ignite
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitCommit writes files to the git repository in dir, creating it on main
+// if needed, commits them and returns the commit.
+func gitCommit(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Setenv("GIT_AUTHOR_NAME", "ignite")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ignite@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "ignite")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ignite@example.com")
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if _, err := gitOutput(filepath.Dir(dir), "init", "-q", "-b", "main", dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeStaged(t, dir, files)
+
+	for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", "templates"}} {
+		if _, err := gitOutput(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return commit
+}
+
+func TestParsePackRef(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(t.TempDir(), "repo")
+	gitCommit(t, repo, map[string]string{"manifest.yaml": "version: 1\n"})
+
+	tests := []struct {
+		value   string
+		want    packRef
+		wantErr bool
+	}{
+		{value: dir, want: packRef{kind: packDir, source: dir, location: dir}},
+		{value: "git+file://" + repo, want: packRef{kind: packGit, source: "git+file://" + repo, location: repo, ref: "HEAD"}},
+		{value: "git+file://" + repo + "@v1.2", want: packRef{kind: packGit, source: "git+file://" + repo, location: repo, ref: "v1.2"}},
+		{value: repo + "@main", want: packRef{kind: packGit, source: repo, location: repo, ref: "main"}},
+		{value: "example.com/blueprints/api", want: packRef{kind: packModule, source: "example.com/blueprints/api", location: "example.com/blueprints/api", ref: "latest"}},
+		{value: "example.com/blueprints/api@v1.4.0", want: packRef{kind: packModule, source: "example.com/blueprints/api", location: "example.com/blueprints/api", ref: "v1.4.0"}},
+		{value: "git+file://remote.example.com/repo", wantErr: true},
+		{value: dir + "/missing@main", wantErr: true},
+		{value: dir + "@main", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePackRef(tt.value)
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePackRef(%q) = %+v, want an error", tt.value, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parsePackRef(%q): %v", tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("parsePackRef(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGitPack(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	repo := filepath.Join(t.TempDir(), "blueprints")
+	first := gitCommit(t, repo, map[string]string{"files/README.md.tmpl": "v1\n"})
+
+	if _, err := gitOutput(repo, "tag", "v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	gitCommit(t, repo, map[string]string{"files/README.md.tmpl": "v2\n"})
+
+	tests := []struct {
+		value   string
+		content string
+	}{
+		{value: "git+file://" + repo + "@v1", content: "v1\n"},
+		{value: "git+file://" + repo, content: "v2\n"},
+		{value: repo + "@" + first, content: "v1\n"},
+	}
+
+	for _, tt := range tests {
+		source, pack, err := openTemplatePack(tt.value)
+		if err != nil {
+			t.Errorf("openTemplatePack(%q): %v", tt.value, err)
+
+			continue
+		}
+
+		content, err := source.read("files/README.md.tmpl")
+		if err != nil || string(content) != tt.content {
+			t.Errorf("%s: template = %q, %v, want %q", tt.value, content, err, tt.content)
+		}
+
+		if pack.kind != packGit || len(pack.commit) != 40 {
+			t.Errorf("%s: pack = %+v, want a git pack at a commit", tt.value, pack)
+		}
+	}
+
+	if _, _, err := openTemplatePack("git+file://" + repo + "@v9"); err == nil {
+		t.Error("opened a pack at an unknown ref")
+	}
+}
+
+func TestGitPackPinnedInProject(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	repo := filepath.Join(t.TempDir(), "blueprints")
+	commit := gitCommit(t, repo, map[string]string{"files/README.md.tmpl": "v1\n"})
+
+	dir := writePlannedProject(t, Options{Module: "api", Database: "postgres", Controller: "http", Templates: "git+file://" + repo + "@main"})
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := lockTemplates{Source: "git+file://" + repo, Ref: "main", Commit: commit}
+	if lock.Templates == nil || lock.Templates.Source != want.Source || lock.Templates.Ref != want.Ref || lock.Templates.Commit != want.Commit {
+		t.Fatalf("templates recorded = %+v, want %+v", lock.Templates, want)
+	}
+
+	gitCommit(t, repo, map[string]string{"files/README.md.tmpl": "v2\n"})
+
+	// check uses the recorded commit, upgrade follows the branch
+	report, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Clean {
+		t.Errorf("check did not use the recorded commit: %v", report.Issues)
+	}
+
+	if _, err := Upgrade(context.Background(), dir, UpgradeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "README.md"))
+	if string(content) != "v2\n" {
+		t.Errorf("README.md = %q after upgrade, want the template of the moved branch", content)
+	}
+}
+
+func TestExtendsChainPinned(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	root := t.TempDir()
+	base := filepath.Join(root, "base")
+	child := filepath.Join(root, "child")
+
+	baseCommit := gitCommit(t, base, map[string]string{"files/README.md.tmpl": "base v1\n"})
+	gitCommit(t, child, map[string]string{
+		"manifest.yaml":       "version: 1\nextends: git+file://" + base + "@main\nstructure:\n  - path: README.md\n    type: file\n  - path: CHILD.md\n    type: file\n",
+		"files/CHILD.md.tmpl": "child\n",
+	})
+
+	dir := writePlannedProject(t, Options{Module: "api", Database: "postgres", Controller: "http", Templates: "git+file://" + child + "@main"})
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := lockExtends{Extends: "git+file://" + base + "@main", Commit: baseCommit}
+	if len(lock.Templates.Extends) != 1 || lock.Templates.Extends[0] != want {
+		t.Fatalf("extends recorded = %+v, want %+v", lock.Templates.Extends, want)
+	}
+
+	gitCommit(t, base, map[string]string{"files/README.md.tmpl": "base v2\n"})
+
+	report, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Clean {
+		t.Errorf("check resolved the extended pack at its moved branch: %v", report.Issues)
+	}
+
+	if _, err := Upgrade(context.Background(), dir, UpgradeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "README.md"))
+	if string(content) != "base v1\n" {
+		t.Errorf("README.md = %q after upgrade, want the extended pack at its recorded commit", content)
+	}
+}
+
+func TestSplitRef(t *testing.T) {
+	tests := []struct{ value, source, ref string }{
+		{"example.com/api@v1.0.0", "example.com/api", "v1.0.0"},
+		{"example.com/api", "example.com/api", ""},
+		{"/srv/user@host/repo", "/srv/user@host/repo", ""},
+		{"../blueprints@main", "../blueprints", "main"},
+	}
+
+	for _, tt := range tests {
+		if source, ref := splitRef(tt.value); source != tt.source || ref != tt.ref {
+			t.Errorf("splitRef(%q) = %q, %q, want %q, %q", tt.value, source, ref, tt.source, tt.ref)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package ignite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// packTestsDir is the directory of a template pack holding its test cases: an
+// answer file <case>.yaml for each case, and the golden tree <case>/ the pack
+// must render for it.
+const packTestsDir = "tests"
+
+// diffContext is the number of unchanged lines around each hunk of a diff.
+const diffContext = 3
+
+// packTestCase is an answer file of a template pack test.
+type packTestCase struct {
+	// Project is the module path the project is generated with. It defaults
+	// to the name of the case.
+	Project string `yaml:"project"`
+	// Variables are the values of the template variables, as given with --set
+	// (the built-in database, controller, workflow and dockerfile included).
+	Variables map[string]string `yaml:"variables"`
+
+	name   string
+	golden string
+}
+
+// PackTestResult is the outcome of a test case.
+type PackTestResult struct {
+	Name string
+	// Updated is set when the golden tree was rewritten.
+	Updated bool
+	// Problems lists the differences between the rendered and golden trees.
+	Problems []string
+}
+
+// PackTestOptions configures TestPack.
+type PackTestOptions struct {
+	// Run, if not empty, is a regular expression selecting the cases to run
+	// by name.
+	Run string
+	// Update rewrites the golden trees from the rendered output instead of
+	// comparing them.
+	Update bool
+}
+
+// TestPack renders the template pack in dir for each of its test cases and
+// compares the output with the case's golden tree.
+//
+// Each answer file tests/<case>.yaml of the pack gives the project and the
+// template variables of a case, and tests/<case>/ holds the files the pack
+// must render for it. Templates missing from the pack are taken from the
+// templates ignite ships with, never from the user's configuration directory,
+// so results do not depend on the machine. A case failing to render is
+// reported as a problem of its result.
+func TestPack(dir string, opts PackTestOptions) ([]*PackTestResult, error) {
+	cases, err := loadPackTests(dir, opts.Run)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*PackTestResult, 0, len(cases))
+
+	for _, tc := range cases {
+		result, err := tc.run(dir, opts.Update)
+		if err != nil {
+			result = &PackTestResult{Name: tc.name, Problems: []string{err.Error()}}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// loadPackTests reads the answer files of the pack in dir, sorted by name, and
+// keeps those whose name matches the regular expression run, if not empty.
+func loadPackTests(dir, run string) ([]*packTestCase, error) {
+	var filter *regexp.Regexp
+
+	if run != "" {
+		var err error
+
+		filter, err = regexp.Compile(run)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --run pattern: %w", err)
+		}
+	}
+
+	testsDir := filepath.Join(dir, packTestsDir)
+
+	answers, err := filepath.Glob(filepath.Join(testsDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(answers)
+
+	var cases []*packTestCase
+
+	for _, answer := range answers {
+		name := strings.TrimSuffix(filepath.Base(answer), ".yaml")
+		if name == "" || name == "." || !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("invalid test case name %q in %s: rename %s", name, testsDir, filepath.Base(answer))
+		}
+
+		if filter != nil && !filter.MatchString(name) {
+			continue
+		}
+
+		tc, err := readPackTest(answer)
+		if err != nil {
+			return nil, err
+		}
+
+		tc.name = name
+		tc.golden = filepath.Join(testsDir, name)
+
+		if tc.Project == "" {
+			tc.Project = name
+		}
+
+		cases = append(cases, tc)
+	}
+
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no test cases in %s (expected <case>.yaml answer files)", testsDir)
+	}
+
+	return cases, nil
+}
+
+func readPackTest(answer string) (*packTestCase, error) {
+	content, err := os.ReadFile(answer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", answer, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var tc packTestCase
+	if err := decoder.Decode(&tc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode %s: %w", answer, err)
+	}
+
+	if tc.Project != "" {
+		if err := ValidateProjectName(tc.Project); err != nil {
+			return nil, fmt.Errorf("%s: %w", answer, err)
+		}
+	}
+
+	return &tc, nil
+}
+
+// run renders the pack in dir for the test case into memory and compares it
+// with the golden tree, or replaces the golden tree with it if
+// update is true.
+func (tc *packTestCase) run(dir string, update bool) (*PackTestResult, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template pack: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template pack %s is not a directory", dir)
+	}
+
+	sources, _, err := openPackChain(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p := newProjectInitializer("", "", false, false)
+	p.projectName = tc.Project
+	p.templates = &templateSet{sources: append(sources, builtinTemplateSources()...)}
+
+	if err := runFlagMode(p, tc.Variables); err != nil {
+		return nil, err
+	}
+
+	plan, err := p.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	result := &PackTestResult{Name: tc.name}
+
+	if update {
+		// the golden tree is replaced as a whole, so it must be a directory of
+		// its own below the tests directory
+		rel, err := filepath.Rel(filepath.Join(dir, packTestsDir), tc.golden)
+		if err != nil || rel == "." || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("golden tree %s is not inside %s", tc.golden, filepath.Join(dir, packTestsDir))
+		}
+
+		if err := os.RemoveAll(tc.golden); err != nil {
+			return nil, fmt.Errorf("failed to remove golden tree: %w", err)
+		}
+
+		if err := createDirectories(plan, DirFS(tc.golden)); err != nil {
+			return nil, err
+		}
+
+		result.Updated = true
+
+		return result, nil
+	}
+
+	rendered := NewMemFS()
+	if err := createDirectories(plan, rendered); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(tc.golden); err != nil {
+		return nil, fmt.Errorf("no golden tree %s (run with --update to create it)", tc.golden)
+	}
+
+	result.Problems, err = compareTrees(os.DirFS(tc.golden), rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// compareTrees compares the files of golden and rendered and describes each
+// difference.
+func compareTrees(golden, rendered fs.FS) ([]string, error) {
+	want, err := treeFiles(golden)
+	if err != nil {
+		return nil, err
+	}
+
+	got, err := treeFiles(rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(want)+len(got))
+	for name := range want {
+		names = append(names, name)
+	}
+
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	var problems []string
+
+	for _, name := range names {
+		wantMode, inGolden := want[name]
+		gotMode, inRendered := got[name]
+
+		switch {
+		case !inRendered:
+			problems = append(problems, "missing file "+name+" (in the golden tree, not rendered)")
+
+			continue
+		case !inGolden:
+			problems = append(problems, "unexpected file "+name+" (rendered, not in the golden tree)")
+
+			continue
+		case wantMode&0o111 != gotMode&0o111:
+			problems = append(problems, fmt.Sprintf("mode of %s: golden %04o, rendered %04o", name, wantMode.Perm(), gotMode.Perm()))
+		}
+
+		a, err := fs.ReadFile(golden, name)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := fs.ReadFile(rendered, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(a, b) {
+			problems = append(problems, unifiedDiff("golden/"+name, "rendered/"+name, a, b))
+		}
+	}
+
+	return problems, nil
+}
+
+// treeFiles returns the mode of every regular file of fsys, by path.
+func treeFiles(fsys fs.FS) (map[string]fs.FileMode, error) {
+	files := make(map[string]fs.FileMode)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		files[name] = info.Mode()
+
+		return nil
+	})
+
+	return files, err
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string
+	// a and b are the indexes of the line in the old and new content, or of
+	// the line that follows it on the side it is missing from.
+	a, b int
+}
+
+// unifiedDiff returns the differences between a and b in the unified format,
+// with diffContext lines of context around each hunk.
+func unifiedDiff(nameA, nameB string, a, b []byte) string {
+	x, y := splitLines(a), splitLines(b)
+	match := matchLines(x, y)
+
+	var ops []diffOp
+
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && match[i] == j:
+			ops = append(ops, diffOp{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && match[i] < 0:
+			ops = append(ops, diffOp{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for start := 0; start < len(ops); {
+		// find the next change and extend the hunk while the following
+		// change is close enough for their contexts to overlap
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+
+		if first == len(ops) {
+			break
+		}
+
+		last := first
+		for next := first + 1; next < len(ops); next++ {
+			if ops[next].kind == ' ' {
+				continue
+			}
+
+			if next-last > 2*diffContext+1 {
+				break
+			}
+
+			last = next
+		}
+
+		from, to := max(first-diffContext, 0), min(last+diffContext+1, len(ops))
+
+		var countA, countB int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+
+			if op.kind != '-' {
+				countB++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(ops[from].a, countA), hunkRange(ops[from].b, countB))
+
+		for _, op := range ops[from:to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = to
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the range of count lines starting at the 0-based index
+// start of a hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,238 @@
+package ignite
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// projectPlan is the fully rendered set of directories and files that make up a
+// project. Building a plan never touches the disk, which lets the same plan be
+// printed in dry-run mode or written by createDirectories.
+type projectPlan struct {
+	items []planItem
+}
+
+type planItem struct {
+	// path is slash-separated and relative to the project root.
+	path    string
+	isDir   bool
+	content []byte
+	// mode is the permission bits of a file, defaultFileMode if zero.
+	mode fs.FileMode
+}
+
+// buildPlan renders every manifest entry into memory using the templates in ts.
+//
+// Entry paths are rendered with data first (see renderPath); entries whose path
+// has an empty element once rendered are skipped. It returns an error naming the
+// entry if its path or template fails to render, or if two manifest entries
+// render to the same path. An entry of a component rendering to the path of a
+// manifest entry gives way to it.
+func buildPlan(ts *templateSet, entries []manifestEntry, data templateData) (*projectPlan, error) {
+	plan := &projectPlan{items: make([]planItem, 0, len(entries))}
+	seen := make(map[string]manifestEntry, len(entries))
+
+	for _, entry := range entries {
+		itemPath, ok, err := renderPath(entry.Path, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render path %s: %w", entry.Path, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		if first, ok := seen[itemPath]; ok {
+			switch {
+			case entry.component != "":
+				continue
+			case first.component != "":
+				plan.remove(itemPath)
+			default:
+				return nil, fmt.Errorf("manifest entries %s and %s both render to %s", first.Path, entry.Path, itemPath)
+			}
+		}
+
+		seen[itemPath] = entry
+
+		item := planItem{path: itemPath, isDir: entry.Type == entryTypeDir}
+
+		if entry.Type == entryTypeFile {
+			file, err := ts.render(entry.template, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render %s: %w", entry.Path, err)
+			}
+
+			if file == nil {
+				continue
+			}
+
+			item.content, item.mode = file.content, file.mode
+		}
+
+		plan.items = append(plan.items, item)
+	}
+
+	return plan, nil
+}
+
+// remove drops the item at path p from the plan.
+func (plan *projectPlan) remove(p string) {
+	for i, item := range plan.items {
+		if item.path == p {
+			plan.items = append(plan.items[:i], plan.items[i+1:]...)
+
+			return
+		}
+	}
+}
+
+// isTemplatedPath reports whether the manifest path p contains template
+// actions.
+func isTemplatedPath(p string) bool {
+	return strings.Contains(p, "{{")
+}
+
+// parsePath parses the manifest path p as a template.
+func parsePath(p string) (*template.Template, error) {
+	return template.New(p).Funcs(templateFuncs).Parse(p)
+}
+
+// renderPath renders the manifest path p with data, so path elements such as
+// internal/{{ .DBType }}/queries follow the project options. It returns false
+// if an element of the rendered path is empty, which skips the entry: the path
+// above is skipped for projects without a database.
+func renderPath(p string, data templateData) (string, bool, error) {
+	if !isTemplatedPath(p) {
+		return p, true, nil
+	}
+
+	tmpl, err := parsePath(p)
+	if err != nil {
+		return "", false, err
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", false, err
+	}
+
+	rendered := buf.String()
+
+	for _, elem := range strings.Split(rendered, "/") {
+		if strings.TrimSpace(elem) == "" {
+			return "", false, nil
+		}
+	}
+
+	if path.Clean(rendered) != rendered || rendered == "." || rendered == ".." || strings.HasPrefix(rendered, "../") {
+		return "", false, fmt.Errorf("rendered path %q must be a clean relative path inside the project", rendered)
+	}
+
+	return rendered, true, nil
+}
+
+// printTree writes the plan as a directory tree rooted at name. If showContent
+// is true the rendered body of every file is printed after the tree. The base
+// snapshots under .ignite/base are not project files and are summed up in a
+// single entry.
+func (plan *projectPlan) printTree(w io.Writer, name string, showContent bool) error {
+	root := newTreeNode(name + "/")
+	snapshots := 0
+
+	for _, item := range plan.items {
+		if item.path == baseDir || strings.HasPrefix(item.path, baseDir+"/") {
+			if !item.isDir {
+				snapshots++
+			}
+
+			// keep the place of .ignite/base among the entries
+			root.child(path.Dir(baseDir) + "/").child(path.Base(baseDir) + "/")
+
+			continue
+		}
+
+		node := root
+		parts := strings.Split(item.path, "/")
+
+		for i, part := range parts {
+			isDir := item.isDir || i < len(parts)-1
+			if isDir {
+				part += "/"
+			}
+
+			node = node.child(part)
+		}
+	}
+
+	if snapshots > 0 {
+		base := root.child(path.Dir(baseDir) + "/").child(path.Base(baseDir) + "/")
+		base.name += fmt.Sprintf(" (snapshots of %d files for ignite upgrade)", snapshots)
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(root.name + "\n")
+	root.write(&buf, "")
+
+	if showContent {
+		for _, item := range plan.items {
+			// base snapshots repeat the files they were taken from
+			if item.isDir || strings.HasPrefix(item.path, baseDir+"/") {
+				continue
+			}
+
+			fmt.Fprintf(&buf, "\n==> %s <==\n", path.Join(name, item.path))
+			buf.Write(item.content)
+
+			if len(item.content) > 0 && !bytes.HasSuffix(item.content, []byte("\n")) {
+				buf.WriteString("\n")
+			}
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+type treeNode struct {
+	name     string
+	children []*treeNode
+}
+
+func newTreeNode(name string) *treeNode {
+	return &treeNode{name: name}
+}
+
+// child returns the child called name, creating it if it does not exist yet.
+// Children keep the order in which they were first added.
+func (n *treeNode) child(name string) *treeNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+
+	c := newTreeNode(name)
+	n.children = append(n.children, c)
+
+	return c
+}
+
+func (n *treeNode) write(buf *bytes.Buffer, prefix string) {
+	for i, c := range n.children {
+		connector, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			connector, indent = "└── ", "    "
+		}
+
+		buf.WriteString(prefix + connector + c.name + "\n")
+		c.write(buf, prefix+indent)
+	}
+}
@@ -0,0 +1,47 @@
+package ignite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintTree(t *testing.T) {
+	plan := &projectPlan{items: []planItem{
+		{path: "cmd", isDir: true},
+		{path: "cmd/main.go", content: []byte("package main")},
+		{path: "README.md", content: []byte("# api\n")},
+		{path: ".ignite", isDir: true},
+		{path: ".ignite/base", isDir: true},
+		{path: ".ignite/base/cmd", isDir: true},
+		{path: ".ignite/base/cmd/main.go", content: []byte("package main")},
+		{path: ".ignite/base/README.md", content: []byte("# api\n")},
+		{path: ".ignite.yaml", content: []byte("files: {}\n")},
+	}}
+
+	var out strings.Builder
+	if err := plan.printTree(&out, "api", true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `api/
+├── cmd/
+│   └── main.go
+├── README.md
+├── .ignite/
+│   └── base/ (snapshots of 2 files for ignite upgrade)
+└── .ignite.yaml
+
+==> api/cmd/main.go <==
+package main
+
+==> api/README.md <==
+# api
+
+==> api/.ignite.yaml <==
+files: {}
+`
+
+	if got := out.String(); got != want {
+		t.Errorf("printTree wrote\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,509 @@
+package ignite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pluginPrefix starts the name of every plugin executable: ignite-<name>.
+const pluginPrefix = "ignite-"
+
+// pluginProtocol is the version of the JSON protocol spoken with plugins.
+const pluginProtocol = 1
+
+// pluginTimeout bounds each call to a plugin.
+const pluginTimeout = time.Minute
+
+// Actions a plugin is asked to perform.
+const (
+	pluginDescribe = "describe"
+	pluginGenerate = "generate"
+)
+
+var componentNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// pluginRequest is written as JSON to the standard input of a plugin.
+type pluginRequest struct {
+	Protocol int    `json:"protocol"`
+	Action   string `json:"action"`
+	// Component, Project and Answers are set for generate.
+	Component string         `json:"component,omitempty"`
+	Project   *pluginProject `json:"project,omitempty"`
+	Answers   map[string]any `json:"answers,omitempty"`
+}
+
+type pluginProject struct {
+	Name   string `json:"name"`
+	Module string `json:"module"`
+	// Variables holds every template variable, the built-in database,
+	// controller, workflow and dockerfile included.
+	Variables map[string]any `json:"variables"`
+}
+
+// pluginResponse is read as JSON from the standard output of a plugin:
+// Components in reply to describe, Files and Directories to generate.
+type pluginResponse struct {
+	Protocol    int               `json:"protocol"`
+	Components  []PluginComponent `json:"components"`
+	Files       []pluginFile      `json:"files"`
+	Directories []string          `json:"directories"`
+	// Error, if set, fails the request with this message.
+	Error string `json:"error"`
+}
+
+// PluginComponent is a component contributed by a plugin.
+type PluginComponent struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Questions are asked when the component is added, like template
+	// variables (see Variable), and their answers sent back with
+	// generate.
+	Questions []Variable `json:"questions"`
+
+	// Plugin is the path of the executable providing the component.
+	Plugin string `json:"-"`
+}
+
+type pluginFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Mode is the octal file mode, 0644 if empty.
+	Mode string `json:"mode"`
+}
+
+// pluginSet is the result of plugin discovery.
+type pluginSet struct {
+	components map[string]*PluginComponent
+	// failed maps the plugins that could not be described to the reason.
+	failed map[string]error
+}
+
+// Plugins returns the components provided by the installed plugins, sorted by
+// name, and the reason each plugin that could not be described, by path,
+// failed.
+func Plugins() ([]*PluginComponent, map[string]error) {
+	plugins := loadPlugins()
+
+	components := make([]*PluginComponent, 0, len(plugins.components))
+	for _, c := range plugins.components {
+		components = append(components, c)
+	}
+
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+
+	return components, plugins.failed
+}
+
+// loadPlugins discovers the installed plugins once per run.
+var loadPlugins = sync.OnceValue(discoverPlugins)
+
+// pluginDirs returns the directories searched for plugins, in order: the
+// ignite/plugins directory of the user's configuration directory, then PATH.
+func pluginDirs() []string {
+	var dirs []string
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "ignite", "plugins"))
+	}
+
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// discoverPlugins finds the ignite-<name> executables in pluginDirs and asks
+// each to describe its components. The first executable of a name wins, as in
+// PATH; components whose name is taken by a built-in component or an earlier
+// plugin are ignored.
+func discoverPlugins() *pluginSet {
+	set := &pluginSet{components: make(map[string]*PluginComponent), failed: make(map[string]error)}
+	seen := make(map[string]bool)
+
+	for _, dir := range pluginDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".exe")
+			if !strings.HasPrefix(name, pluginPrefix) || seen[name] {
+				continue
+			}
+
+			executable := filepath.Join(dir, entry.Name())
+			if !isExecutable(executable) {
+				continue
+			}
+
+			seen[name] = true
+
+			components, err := describePlugin(executable)
+			if err != nil {
+				log.Printf("Ignoring plugin %s: %v", executable, err)
+				set.failed[executable] = err
+
+				continue
+			}
+
+			for _, c := range components {
+				if _, ok := set.components[c.Name]; ok || isBuiltinComponent(c.Name) {
+					log.Printf("Ignoring component %s of plugin %s: the name is already taken", c.Name, executable)
+
+					continue
+				}
+
+				set.components[c.Name] = c
+			}
+		}
+	}
+
+	return set
+}
+
+func isExecutable(file string) bool {
+	info, err := os.Stat(file)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
+
+// isBuiltinComponent reports whether name is taken by a component of the
+// registry or by an option of ignite add.
+func isBuiltinComponent(name string) bool {
+	if _, ok := registry.lookup(name); ok {
+		return true
+	}
+
+	return isSupported(builtinVariables, name)
+}
+
+// describePlugin asks the plugin executable for its components and validates
+// them.
+func describePlugin(executable string) ([]*PluginComponent, error) {
+	resp, err := callPlugin(executable, pluginRequest{Protocol: pluginProtocol, Action: pluginDescribe})
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]*PluginComponent, 0, len(resp.Components))
+
+	for i := range resp.Components {
+		c := &resp.Components[i]
+
+		if !componentNamePattern.MatchString(c.Name) {
+			return nil, fmt.Errorf("component #%d: invalid name %q: must match %s", i+1, c.Name, componentNamePattern)
+		}
+
+		var declared []string
+
+		for j := range c.Questions {
+			q := &c.Questions[j]
+
+			if err := q.validate(declared); err != nil {
+				return nil, fmt.Errorf("component %s: question #%d (%s): %w", c.Name, j+1, q.Name, err)
+			}
+
+			declared = append(declared, q.Name)
+		}
+
+		c.Plugin = executable
+		components = append(components, c)
+	}
+
+	return components, nil
+}
+
+// callPlugin sends req to the plugin executable and decodes its response.
+// The plugin's standard error goes to the log.
+func callPlugin(executable string, req pluginRequest) (*pluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, executable)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+
+	if stderr.Len() > 0 {
+		log.Printf("%s: %s", filepath.Base(executable), strings.TrimSpace(stderr.String()))
+	}
+
+	if runErr != nil {
+		return nil, fmt.Errorf("%s failed: %v: %s", req.Action, runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %w", req.Action, err)
+	}
+
+	if resp.Protocol != pluginProtocol {
+		return nil, fmt.Errorf("unsupported protocol version %d (expected %d)", resp.Protocol, pluginProtocol)
+	}
+
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	return &resp, nil
+}
+
+// lookupPluginComponent returns the installed plugin component called name.
+func lookupPluginComponent(name string) (*PluginComponent, error) {
+	plugins := loadPlugins()
+
+	if c, ok := plugins.components[name]; ok {
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("no plugin provides the component %s (run ignite plugins list to see the installed ones)", name)
+}
+
+// resolveAnswers validates the answers given for the component's questions,
+// asking for the others with ask, if not nil, and falling back to their
+// defaults. Answers are given as --set <component>.<question>=<value>.
+func (c *PluginComponent) resolveAnswers(p *projectInitializer, given map[string]string, ask PromptFunc) (map[string]any, error) {
+	schema := &variableSchema{Version: schemaVersion, Variables: c.Questions}
+
+	for name := range given {
+		if !isSupported(schema.names(), name) {
+			return nil, fmt.Errorf("component %s has no question %s (questions: %s)", c.Name, name, strings.Join(schema.names(), ", "))
+		}
+	}
+
+	values, err := p.resolveVariables(schema, given, ask)
+	if err != nil {
+		return nil, fmt.Errorf("component %s: %w (answers are set with --set %s.<question>=<value>)", c.Name, err, c.Name)
+	}
+
+	answers := make(map[string]any, len(c.Questions))
+	for _, q := range c.Questions {
+		if value, ok := values[q.Name]; ok {
+			answers[q.Name] = value
+		}
+	}
+
+	return answers, nil
+}
+
+// addable returns the component as one `ignite add` can add, answering its
+// questions with given and, if ask is not nil, by asking.
+func (c *PluginComponent) addable(given map[string]string, ask PromptFunc) addableComponent {
+	return addableComponent{
+		name:  c.Name,
+		usage: c.Name,
+		apply: func(p *projectInitializer, _ string) error {
+			if _, ok := p.plugins[c.Name]; ok {
+				return errComponentPresent
+			}
+
+			answers, err := c.resolveAnswers(p, given, ask)
+			if err != nil {
+				return err
+			}
+
+			// the map may be shared with other initializers of the project
+			p.plugins = maps.Clone(p.plugins)
+			if p.plugins == nil {
+				p.plugins = make(map[string]map[string]any)
+			}
+
+			p.plugins[c.Name] = answers
+
+			return nil
+		},
+	}
+}
+
+// generate asks the plugin for the files and directories of the component.
+func (c *PluginComponent) generate(p *projectInitializer, answers map[string]any) (*projectPlan, error) {
+	resp, err := callPlugin(c.Plugin, pluginRequest{
+		Protocol:  pluginProtocol,
+		Action:    pluginGenerate,
+		Component: c.Name,
+		Project: &pluginProject{
+			Name:      p.directoryName(),
+			Module:    p.projectName,
+			Variables: p.templateData().Vars,
+		},
+		Answers: answers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", filepath.Base(c.Plugin), err)
+	}
+
+	plan := &projectPlan{}
+
+	for _, dir := range resp.Directories {
+		if err := validatePluginPath(dir); err != nil {
+			return nil, fmt.Errorf("plugin %s: directory %q: %w", filepath.Base(c.Plugin), dir, err)
+		}
+
+		plan.items = append(plan.items, planItem{path: dir, isDir: true})
+	}
+
+	for _, file := range resp.Files {
+		if err := validatePluginPath(file.Path); err != nil {
+			return nil, fmt.Errorf("plugin %s: file %q: %w", filepath.Base(c.Plugin), file.Path, err)
+		}
+
+		item := planItem{path: file.Path, content: []byte(file.Content), mode: defaultFileMode}
+
+		if file.Mode != "" {
+			mode, err := strconv.ParseUint(file.Mode, 8, 32)
+			if err != nil || mode > 0o777 {
+				return nil, fmt.Errorf("plugin %s: file %q: invalid mode %q", filepath.Base(c.Plugin), file.Path, file.Mode)
+			}
+
+			item.mode = os.FileMode(mode)
+		}
+
+		plan.items = append(plan.items, item)
+	}
+
+	return plan, nil
+}
+
+// validatePluginPath checks that a path returned by a plugin stays inside the
+// project and does not touch the ignite metadata.
+func validatePluginPath(p string) error {
+	if p == "" || path.IsAbs(p) || path.Clean(p) != p || p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return fmt.Errorf("must be a clean relative path inside the project")
+	}
+
+	if p == lockFileName || p == path.Dir(baseDir) || strings.HasPrefix(p, path.Dir(baseDir)+"/") {
+		return fmt.Errorf("must not be ignite metadata")
+	}
+
+	return nil
+}
+
+// withComponents adds the files and directories of the initializer's plugin
+// components to plan, in component name order. A path planned twice is an
+// error.
+func (p *projectInitializer) withComponents(plan *projectPlan) (*projectPlan, error) {
+	if len(p.plugins) == 0 {
+		return plan, nil
+	}
+
+	names := make([]string, 0, len(p.plugins))
+	for name := range p.plugins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	owners := make(map[string]string, len(plan.items))
+	for _, item := range plan.items {
+		owners[item.path] = "the templates"
+	}
+
+	for _, name := range names {
+		c, err := lookupPluginComponent(name)
+		if err != nil {
+			return nil, err
+		}
+
+		contributed, err := c.generate(p, p.plugins[name])
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", name, err)
+		}
+
+		for _, item := range contributed.items {
+			if owner, ok := owners[item.path]; ok {
+				// a directory may be shared
+				if item.isDir {
+					continue
+				}
+
+				return nil, fmt.Errorf("component %s: %s is already generated by %s", name, item.path, owner)
+			}
+
+			owners[item.path] = "component " + name
+			plan.items = append(plan.items, item)
+		}
+	}
+
+	return plan, nil
+}
+
+// enableComponents adds the plugin components named in with to the
+// initializer, resolving their answers from given (the --set values whose name
+// is <component>.<question>) and, if ask is not nil, by asking for them.
+func (p *projectInitializer) enableComponents(with []string, given map[string]map[string]string, ask PromptFunc) error {
+	for name := range given {
+		if !isSupported(with, name) {
+			return fmt.Errorf("answers are given for component %s, which is not added with --with", name)
+		}
+	}
+
+	for _, name := range with {
+		c, err := lookupPluginComponent(name)
+		if err != nil {
+			return err
+		}
+
+		answers, err := c.resolveAnswers(p, given[name], ask)
+		if err != nil {
+			return err
+		}
+
+		if p.plugins == nil {
+			p.plugins = make(map[string]map[string]any)
+		}
+
+		p.plugins[name] = answers
+	}
+
+	return nil
+}
+
+// splitComponentAnswers separates the --set values for plugin components,
+// named <component>.<question>, from the template variables.
+func splitComponentAnswers(given map[string]string) (map[string]string, map[string]map[string]string) {
+	variables := make(map[string]string, len(given))
+	answers := make(map[string]map[string]string)
+
+	for name, value := range given {
+		component, question, ok := strings.Cut(name, ".")
+		if !ok {
+			variables[name] = value
+
+			continue
+		}
+
+		if answers[component] == nil {
+			answers[component] = make(map[string]string)
+		}
+
+		answers[component][question] = value
+	}
+
+	return variables, answers
+}
@@ -0,0 +1,212 @@
+package ignite
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePlugin installs a plugin called name in the user's plugin directory
+// of config. It saves every request it reads to <name>.request next to it and
+// replies with describe or generate.
+func writePlugin(t *testing.T, config, name, describe, generate string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	executable := filepath.Join(config, "ignite", "plugins", pluginPrefix+name)
+	script := "#!/bin/sh\nIFS= read -r request\nprintf '%s' \"$request\" > \"$0.request\"\n" +
+		"case \"$request\" in\n" +
+		"*'\"action\":\"describe\"'*) printf '%s' '" + describe + "' ;;\n" +
+		"*) printf '%s' '" + generate + "' ;;\n" +
+		"esac\n"
+
+	writeStaged(t, filepath.Dir(executable), map[string]string{filepath.Base(executable): script})
+
+	if err := os.Chmod(executable, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	return executable
+}
+
+// usePlugins makes the plugins of the test's configuration directory the
+// installed ones, keeping the plugins of the machine out of the way.
+func usePlugins(t *testing.T) string {
+	t.Helper()
+
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("PATH", t.TempDir())
+
+	load := loadPlugins
+	loadPlugins = discoverPlugins
+
+	t.Cleanup(func() { loadPlugins = load })
+
+	return config
+}
+
+const auditDescribe = `{"protocol":1,"components":[{"name":"audit","description":"Audit logging","questions":[{"name":"level","type":"string","options":["info","debug"],"default":"info"}]}]}`
+
+func TestDiscoverPlugins(t *testing.T) {
+	config := usePlugins(t)
+
+	audit := writePlugin(t, config, "audit", auditDescribe, "")
+	writePlugin(t, config, "failing", `{"protocol":1,"error":"not configured"}`, "")
+	writePlugin(t, config, "future", `{"protocol":2,"components":[]}`, "")
+	writePlugin(t, config, "invalid", `{"protocol":1,"components":[{"name":"Audit"}]}`, "")
+	writePlugin(t, config, "shadowing", `{"protocol":1,"components":[{"name":"postgres"},{"name":"audit"}]}`, "")
+
+	components, failed := Plugins()
+
+	if len(components) != 1 || components[0].Name != "audit" || components[0].Plugin != audit || len(components[0].Questions) != 1 {
+		t.Errorf("components = %+v, want audit from %s", components, audit)
+	}
+
+	wantFailed := map[string]string{
+		"failing": "not configured",
+		"future":  "unsupported protocol version 2",
+		"invalid": "invalid name",
+	}
+
+	for name, want := range wantFailed {
+		err := failed[filepath.Join(config, "ignite", "plugins", pluginPrefix+name)]
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want it to contain %q", name, err, want)
+		}
+	}
+
+	if len(failed) != len(wantFailed) {
+		t.Errorf("failed = %v, want %d plugins", failed, len(wantFailed))
+	}
+
+	var request pluginRequest
+	if content, err := os.ReadFile(audit + ".request"); err != nil || json.Unmarshal(content, &request) != nil {
+		t.Fatalf("describe request = %q, %v", content, err)
+	}
+
+	if request.Protocol != pluginProtocol || request.Action != pluginDescribe {
+		t.Errorf("describe request = %+v", request)
+	}
+}
+
+func TestPluginComponentInProject(t *testing.T) {
+	config := usePlugins(t)
+
+	audit := writePlugin(t, config, "audit", auditDescribe,
+		`{"protocol":1,"directories":["audit"],"files":[{"path":"audit/audit.go","content":"package audit\n"},{"path":"scripts/rotate.sh","content":"#!/bin/sh\n","mode":"0755"}]}`)
+
+	result, err := Plan(Options{
+		Module:     "github.com/acme/api",
+		Database:   "postgres",
+		Controller: "http",
+		With:       []string{"audit"},
+		Variables:  map[string]string{"audit.level": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	files := make(map[string]File)
+	for _, f := range result.Files {
+		files[f.Path] = f
+	}
+
+	if f, ok := files["audit/audit.go"]; !ok || string(f.Content) != "package audit\n" {
+		t.Errorf("audit/audit.go = %+v, want the plugin's file", f)
+	}
+
+	if f := files["scripts/rotate.sh"]; f.Mode != 0o755 {
+		t.Errorf("scripts/rotate.sh mode = %v, want 0755", f.Mode)
+	}
+
+	var request pluginRequest
+	if content, err := os.ReadFile(audit + ".request"); err != nil || json.Unmarshal(content, &request) != nil {
+		t.Fatalf("generate request = %q, %v", content, err)
+	}
+
+	if request.Action != pluginGenerate || request.Component != "audit" || request.Answers["level"] != "debug" {
+		t.Errorf("generate request = %+v, want audit with level debug", request)
+	}
+
+	if request.Project == nil || request.Project.Module != "github.com/acme/api" || request.Project.Name != "api" || request.Project.Variables["database"] != "postgres" {
+		t.Errorf("generate request project = %+v", request.Project)
+	}
+}
+
+func TestPluginComponentErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate string
+		answers  map[string]string
+		wantErr  string
+	}{
+		{name: "unknown question", answers: map[string]string{"audit.verbose": "yes"}, wantErr: "has no question verbose"},
+		{name: "invalid answer", answers: map[string]string{"audit.level": "trace"}, wantErr: "level"},
+		{name: "path outside", generate: `{"protocol":1,"files":[{"path":"../x.go"}]}`, wantErr: "inside the project"},
+		{name: "metadata", generate: `{"protocol":1,"files":[{"path":".ignite.yaml"}]}`, wantErr: "ignite metadata"},
+		{name: "template file", generate: `{"protocol":1,"files":[{"path":"README.md"}]}`, wantErr: "already generated by the templates"},
+		{name: "invalid mode", generate: `{"protocol":1,"files":[{"path":"x.sh","mode":"0999"}]}`, wantErr: "invalid mode"},
+		{name: "plugin error", generate: `{"protocol":1,"error":"no license"}`, wantErr: "no license"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := usePlugins(t)
+
+			writePlugin(t, config, "audit", auditDescribe, tt.generate)
+
+			_, err := Plan(Options{
+				Module:     "github.com/acme/api",
+				Database:   "postgres",
+				Controller: "http",
+				With:       []string{"audit"},
+				Variables:  tt.answers,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePluginPath(t *testing.T) {
+	tests := map[string]bool{
+		"internal/audit/audit.go": true,
+		"Makefile":                true,
+		"":                        false,
+		".":                       false,
+		"..":                      false,
+		"../x":                    false,
+		"/etc/passwd":             false,
+		"a//b":                    false,
+		"a/./b":                   false,
+		".ignite.yaml":            false,
+		".ignite":                 false,
+		".ignite/base/go.mod":     false,
+	}
+
+	for p, want := range tests {
+		if err := validatePluginPath(p); (err == nil) != want {
+			t.Errorf("validatePluginPath(%q) = %v, want valid %v", p, err, want)
+		}
+	}
+}
+
+func TestSplitComponentAnswers(t *testing.T) {
+	variables, answers := splitComponentAnswers(map[string]string{"license": "mit", "audit.level": "debug", "audit.sink": "stdout"})
+
+	if len(variables) != 1 || variables["license"] != "mit" {
+		t.Errorf("variables = %v, want license", variables)
+	}
+
+	if len(answers) != 1 || len(answers["audit"]) != 2 || answers["audit"]["level"] != "debug" {
+		t.Errorf("answers = %v, want audit.level and audit.sink", answers)
+	}
+}
@@ -0,0 +1,384 @@
+package ignite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	schemaName    = "variables.yaml"
+	schemaVersion = 1
+
+	variableString = "string"
+	variableBool   = "bool"
+	variableInt    = "int"
+)
+
+// builtinVariables are the variables backed by the initializer's own options
+// and flags (-d, -c, --withWorkflow, --withDockerfile), which select the
+// components. Every other variable of the schema is stored in
+// projectInitializer.vars.
+var builtinVariables = []string{"database", "controller", "workflow", "dockerfile"}
+
+// variableSchema declares the variables a template source asks for. Each
+// variable becomes a prompt in interactive mode and can be set with
+// --set name=value.
+type variableSchema struct {
+	Version   int        `yaml:"version"`
+	Variables []Variable `yaml:"variables"`
+}
+
+// Variable is a template variable declared in a variable schema, or a
+// question of a component.
+type Variable struct {
+	Name string `yaml:"name"`
+	// Type is one of string (the default), bool or int.
+	Type string `yaml:"type"`
+	// Prompt is the question asked in interactive mode, Help describes the
+	// variable in errors and documentation.
+	Prompt  string `yaml:"prompt"`
+	Help    string `yaml:"help"`
+	Default string `yaml:"default"`
+	// Options lists the allowed values, if restricted.
+	Options []string `yaml:"options"`
+	// Pattern is a regular expression values must match.
+	Pattern  string `yaml:"pattern"`
+	Required bool   `yaml:"required"`
+	// When lists conditions on earlier variables, in the same `name` or
+	// `name=value` form as manifest conditions. The variable is only asked
+	// for, and only accepted, if they all hold.
+	When []string `yaml:"when"`
+
+	pattern *regexp.Regexp
+}
+
+// PromptFunc asks for the value of the variable v, given the values of the
+// variables resolved before it, and returns the raw answer. An empty answer
+// leaves the variable to its default.
+type PromptFunc func(v *Variable, values map[string]any) (string, error)
+
+// loadSchema reads the variable schema from the template set, followed by the
+// questions of the components it does not declare itself. Template sources
+// without a schema declare no variables of their own.
+func loadSchema(ts *templateSet) (*variableSchema, error) {
+	schema := &variableSchema{Version: schemaVersion}
+
+	content, source, err := ts.lookup(schemaName)
+	if err == nil {
+		schema, err = parseSchema(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s variable schema: %w", source.label, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read variable schema: %w", err)
+	}
+
+	for _, q := range registry.questions() {
+		if !isSupported(schema.names(), q.Name) {
+			schema.Variables = append(schema.Variables, q)
+		}
+	}
+
+	return schema, nil
+}
+
+// parseSchema decodes a variable schema and validates every variable in it.
+func parseSchema(content []byte) (*variableSchema, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var s variableSchema
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode variable schema: %w", err)
+	}
+
+	if s.Version != schemaVersion {
+		return nil, fmt.Errorf("unsupported variable schema version %d (expected %d)", s.Version, schemaVersion)
+	}
+
+	var declared []string
+
+	for i := range s.Variables {
+		v := &s.Variables[i]
+
+		// the options of the built-in variables are the components
+		if choices := registry.choices(v.Name); len(choices) > 0 {
+			for _, option := range v.Options {
+				if !isSupported(choices, option) {
+					return nil, fmt.Errorf("variable #%d (%s): unknown component %q (one of: %s)", i+1, v.Name, option, strings.Join(choices, ", "))
+				}
+			}
+
+			if len(v.Options) == 0 {
+				v.Options = choices
+			}
+		}
+
+		if err := v.validate(declared); err != nil {
+			return nil, fmt.Errorf("variable #%d (%s): %w", i+1, v.Name, err)
+		}
+
+		declared = append(declared, v.Name)
+	}
+
+	return &s, nil
+}
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// validate checks the declaration of v. Its conditions may only refer to the
+// built-in variables and the variables declared before it.
+func (v *Variable) validate(declared []string) error {
+	if !variableNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("invalid name %q: must start with a letter and contain only letters, digits and underscores", v.Name)
+	}
+
+	if isSupported(declared, v.Name) {
+		return fmt.Errorf("duplicate variable")
+	}
+
+	switch v.Type {
+	case "":
+		v.Type = variableString
+	case variableString, variableBool, variableInt:
+	default:
+		return fmt.Errorf("unknown type %q (one of: %s, %s, %s)", v.Type, variableString, variableBool, variableInt)
+	}
+
+	if v.Pattern != "" {
+		pattern, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+
+		v.pattern = pattern
+	}
+
+	for _, option := range v.Options {
+		if _, err := v.Parse(option); err != nil {
+			return fmt.Errorf("invalid option: %w", err)
+		}
+	}
+
+	if v.Default != "" {
+		if _, err := v.Parse(v.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+
+	for _, cond := range v.When {
+		if _, _, err := parseCondition(cond, append(declared[:len(declared):len(declared)], builtinVariables...)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parse converts raw to the variable's type and checks it against its options
+// and pattern.
+func (v *Variable) Parse(raw string) (any, error) {
+	if len(v.Options) > 0 && !isSupported(v.Options, raw) {
+		return nil, fmt.Errorf("%q is not one of: %s", raw, strings.Join(v.Options, ", "))
+	}
+
+	if v.pattern != nil && !v.pattern.MatchString(raw) {
+		return nil, fmt.Errorf("%q does not match %s", raw, v.Pattern)
+	}
+
+	switch v.Type {
+	case variableBool:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y":
+			return true, nil
+		case "false", "no", "n":
+			return false, nil
+		}
+
+		return nil, fmt.Errorf("%q is not a boolean (yes/no)", raw)
+	case variableInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+
+		return n, nil
+	default:
+		return raw, nil
+	}
+}
+
+// names returns the names of the declared variables.
+func (s *variableSchema) names() []string {
+	names := make([]string, 0, len(s.Variables))
+	for _, v := range s.Variables {
+		names = append(names, v.Name)
+	}
+
+	return names
+}
+
+// applies reports whether the conditions of v hold for the values resolved so
+// far.
+func (v *Variable) applies(values map[string]any) bool {
+	options := make(map[string]string, len(values))
+	for name, value := range values {
+		options[name] = optionString(value)
+	}
+
+	return conditionsHold(v.When, options)
+}
+
+// resolveVariables validates the raw values given for the variables of schema
+// (from flags or prompts) and returns the typed value of every variable that
+// applies: the given value, or else its default. It fails for unknown
+// variables, invalid values, values given for variables that do not apply, and
+// missing required values. ask, if not nil, is called for applicable variables
+// without a given value and returns the raw answer to use.
+//
+// Built-in variables the schema does not declare can still be given, and
+// otherwise keep the initializer's values, so conditions can refer to them.
+func (p *projectInitializer) resolveVariables(s *variableSchema, given map[string]string, ask PromptFunc) (map[string]any, error) {
+	var unknown []string
+
+	for name := range given {
+		if !isSupported(s.names(), name) && !isSupported(builtinVariables, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+
+		return nil, fmt.Errorf("unknown variable %s (declared: %s)", strings.Join(unknown, ", "), strings.Join(s.names(), ", "))
+	}
+
+	values := make(map[string]any, len(s.Variables))
+
+	for name, value := range p.builtinValues() {
+		if isSupported(s.names(), name) {
+			continue
+		}
+
+		if raw, ok := given[name]; ok {
+			parsed, err := builtinVariable(name).Parse(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for variable %s: %w", name, err)
+			}
+
+			value = parsed
+		}
+
+		values[name] = value
+	}
+
+	for i := range s.Variables {
+		v := &s.Variables[i]
+
+		raw, ok := given[v.Name]
+
+		if !v.applies(values) {
+			if ok {
+				return nil, fmt.Errorf("variable %s only applies when %s", v.Name, strings.Join(v.When, ", "))
+			}
+
+			continue
+		}
+
+		if !ok && ask != nil {
+			answer, err := ask(v, values)
+			if err != nil {
+				return nil, err
+			}
+
+			raw, ok = answer, answer != ""
+		}
+
+		if !ok {
+			raw, ok = v.Default, v.Default != ""
+		}
+
+		if !ok {
+			if v.Required {
+				return nil, fmt.Errorf("missing value for variable %s (set it with --set %s=<value>)", v.Name, v.Name)
+			}
+
+			continue
+		}
+
+		value, err := v.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for variable %s: %w", v.Name, err)
+		}
+
+		values[v.Name] = value
+	}
+
+	return values, nil
+}
+
+// builtinVariable declares the built-in variable called name for schemas that
+// do not declare it themselves.
+func builtinVariable(name string) *Variable {
+	v := &Variable{Name: name, Type: variableString}
+
+	if v.Options = registry.choices(name); len(v.Options) == 0 {
+		v.Type = variableBool
+	}
+
+	return v
+}
+
+// builtinValues returns the values of the built-in variables.
+func (p *projectInitializer) builtinValues() map[string]any {
+	return map[string]any{
+		"database":   p.dbType,
+		"controller": p.controlType,
+		"workflow":   p.withWorkflow,
+		"dockerfile": p.withDockerfile,
+	}
+}
+
+// applyVariables stores resolved variable values on the initializer: built-in
+// variables update its options, the others are kept in vars.
+func (p *projectInitializer) applyVariables(values map[string]any) {
+	for name, value := range values {
+		switch name {
+		case "database":
+			p.dbType, _ = value.(string)
+		case "controller":
+			p.controlType, _ = value.(string)
+		case "workflow":
+			p.withWorkflow, _ = value.(bool)
+		case "dockerfile":
+			p.withDockerfile, _ = value.(bool)
+		default:
+			if p.vars == nil {
+				p.vars = make(map[string]any)
+			}
+
+			p.vars[name] = value
+		}
+	}
+}
+
+// optionString is the form of a variable value used in conditions: false and
+// unset values are empty.
+func optionString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return boolOption(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,236 @@
+package ignite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		// wantErr is a substring of the expected error, empty if the schema
+		// is valid.
+		wantErr string
+	}{
+		{
+			name: "valid",
+			schema: `version: 1
+variables:
+  - name: team
+    pattern: "^[a-z]+$"
+    default: payments
+  - name: replicas
+    type: int
+    default: "2"
+  - name: metrics
+    type: bool
+    when: [team=payments]
+  - name: tier
+    options: [gold, silver]
+    when: [database=postgres]
+`,
+		},
+		{
+			name:    "unsupported version",
+			schema:  "version: 2\n",
+			wantErr: "unsupported variable schema version 2",
+		},
+		{
+			name:    "unknown field",
+			schema:  "version: 1\nvariables:\n  - name: team\n    kind: string\n",
+			wantErr: "field kind not found",
+		},
+		{
+			name:    "invalid name",
+			schema:  "version: 1\nvariables:\n  - name: 1team\n",
+			wantErr: `invalid name "1team"`,
+		},
+		{
+			name:    "duplicate",
+			schema:  "version: 1\nvariables:\n  - name: team\n  - name: team\n",
+			wantErr: "duplicate variable",
+		},
+		{
+			name:    "unknown type",
+			schema:  "version: 1\nvariables:\n  - name: team\n    type: float\n",
+			wantErr: `unknown type "float"`,
+		},
+		{
+			name:    "invalid pattern",
+			schema:  "version: 1\nvariables:\n  - name: team\n    pattern: \"[\"\n",
+			wantErr: "invalid pattern",
+		},
+		{
+			name:    "option of the wrong type",
+			schema:  "version: 1\nvariables:\n  - name: replicas\n    type: int\n    options: [\"1\", many]\n",
+			wantErr: "invalid option",
+		},
+		{
+			name:    "default not among the options",
+			schema:  "version: 1\nvariables:\n  - name: tier\n    options: [gold]\n    default: bronze\n",
+			wantErr: "invalid default",
+		},
+		{
+			name:    "condition on a later variable",
+			schema:  "version: 1\nvariables:\n  - name: metrics\n    when: [team]\n  - name: team\n",
+			wantErr: "variable #1 (metrics)",
+		},
+		{
+			name:    "unknown component",
+			schema:  "version: 1\nvariables:\n  - name: database\n    options: [oracle]\n",
+			wantErr: `unknown component "oracle"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSchema([]byte(tt.schema))
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("parseSchema: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("parseSchema succeeded, want an error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("parseSchema error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSchemaDefaults(t *testing.T) {
+	s, err := parseSchema([]byte("version: 1\nvariables:\n  - name: team\n  - name: database\n"))
+	if err != nil {
+		t.Fatalf("parseSchema: %v", err)
+	}
+
+	if got := s.Variables[0].Type; got != variableString {
+		t.Errorf("default type = %q, want %q", got, variableString)
+	}
+
+	if got, want := strings.Join(s.Variables[1].Options, ","), strings.Join(Choices("database"), ","); got != want {
+		t.Errorf("database options = %q, want the components %q", got, want)
+	}
+}
+
+func TestVariableParse(t *testing.T) {
+	s, err := parseSchema([]byte(`version: 1
+variables:
+  - name: team
+    pattern: "^[a-z]+$"
+  - name: replicas
+    type: int
+  - name: metrics
+    type: bool
+  - name: tier
+    options: [gold, silver]
+`))
+	if err != nil {
+		t.Fatalf("parseSchema: %v", err)
+	}
+
+	team, replicas, metrics, tier := &s.Variables[0], &s.Variables[1], &s.Variables[2], &s.Variables[3]
+
+	tests := []struct {
+		v       *Variable
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{v: team, raw: "payments", want: "payments"},
+		{v: team, raw: "Payments", wantErr: true},
+		{v: replicas, raw: "3", want: 3},
+		{v: replicas, raw: "three", wantErr: true},
+		{v: metrics, raw: "yes", want: true},
+		{v: metrics, raw: "False", want: false},
+		{v: metrics, raw: "maybe", wantErr: true},
+		{v: tier, raw: "gold", want: "gold"},
+		{v: tier, raw: "bronze", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.v.Parse(tt.raw)
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: Parse(%q) = %v, want an error", tt.v.Name, tt.raw, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", tt.v.Name, tt.raw, err)
+		} else if got != tt.want {
+			t.Errorf("%s: Parse(%q) = %v, want %v", tt.v.Name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFlagModeVariables(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templates := t.TempDir()
+
+	writeStaged(t, templates, map[string]string{
+		"variables.yaml": `version: 1
+variables:
+  - name: database
+  - name: controller
+  - name: team
+    required: true
+  - name: replicas
+    type: int
+    default: "2"
+  - name: metrics
+    type: bool
+    when: [database=postgres]
+`,
+	})
+
+	tests := []struct {
+		name    string
+		given   map[string]string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			given: map[string]string{"team": "payments"},
+			want:  map[string]any{"team": "payments", "replicas": 2, "database": "postgres"},
+		},
+		{
+			name:  "given values",
+			given: map[string]string{"team": "payments", "replicas": "5", "metrics": "yes"},
+			want:  map[string]any{"replicas": 5, "metrics": true},
+		},
+		{name: "required missing", given: map[string]string{}, wantErr: "team"},
+		{name: "invalid value", given: map[string]string{"team": "payments", "replicas": "many"}, wantErr: "replicas"},
+		{name: "unknown variable", given: map[string]string{"team": "payments", "region": "eu"}, wantErr: "region"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Plan(Options{Module: "api", Database: "postgres", Controller: "http", Templates: templates, Variables: tt.given})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to mention %s", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+
+			for name, want := range tt.want {
+				if got := result.Variables[name]; got != want {
+					t.Errorf("%s = %v (%T), want %v (%T)", name, got, got, want, want)
+				}
+			}
+		})
+	}
+}
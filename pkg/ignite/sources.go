@@ -0,0 +1,476 @@
+package ignite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Labels of the places templates are looked up in, from highest to lowest
+// priority.
+const (
+	sourceFlag       = "--templates"
+	sourceUserConfig = "user config"
+	sourceEmbedded   = "embedded"
+)
+
+// templateSource is a place templates are looked up in. Template names are
+// slash-separated paths relative to the source's root, which holds the
+// manifest.yaml and a files directory mirroring the generated project.
+type templateSource struct {
+	label string
+	// dir is the directory templates are read from, empty for the embedded
+	// templates.
+	dir  string
+	read func(name string) ([]byte, error)
+	list func() ([]string, error)
+}
+
+// templateSet resolves template names through a chain of sources. The first
+// source that has a template wins, so files in a user-provided directory
+// replace the embedded template of the same name.
+type templateSet struct {
+	sources []templateSource
+	// pack is the template pack the --templates flag refers to, if any.
+	pack *templatePack
+}
+
+// newTemplateSet returns the template lookup chain:
+//
+//   - flag, if not empty (the --templates flag): a directory, which must
+//     exist, or a git or Go module template pack (see openTemplatePack),
+//     followed by the packs it extends (see openPackChain).
+//   - the ignite/templates directory in the user's configuration directory
+//     (e.g. ~/.config/ignite/templates), if it exists.
+//   - the templates embedded in the binary.
+func newTemplateSet(flag string) (*templateSet, error) {
+	return newPinnedTemplateSet(flag, nil)
+}
+
+// newPinnedTemplateSet is newTemplateSet with the packs flag extends used at
+// the commits or module versions pins maps their extends values to.
+func newPinnedTemplateSet(flag string, pins map[string]string) (*templateSet, error) {
+	ts := &templateSet{}
+
+	if flag != "" {
+		sources, pack, err := openPackChain(flag, pins)
+		if err != nil {
+			return nil, err
+		}
+
+		ts.sources = append(ts.sources, sources...)
+		ts.pack = pack
+	}
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		userDir := filepath.Join(configDir, "ignite", "templates")
+
+		if info, err := os.Stat(userDir); err == nil && info.IsDir() {
+			ts.sources = append(ts.sources, dirTemplateSource(sourceUserConfig, userDir))
+		}
+	}
+
+	ts.sources = append(ts.sources, builtinTemplateSources()...)
+
+	return ts, nil
+}
+
+// embeddedTemplateSet returns a template set holding only the templates ignite
+// ships with.
+func embeddedTemplateSet() *templateSet {
+	return &templateSet{sources: builtinTemplateSources()}
+}
+
+// builtinTemplateSources returns the sources of the templates ignite ships
+// with: those of the registered components (see ComponentTemplates), then the
+// embedded ones.
+func builtinTemplateSources() []templateSource {
+	var sources []templateSource
+
+	for _, c := range registry.components {
+		if ct, ok := c.(ComponentTemplates); ok {
+			sources = append(sources, componentTemplateSource(c.Name(), ct.Templates()))
+		}
+	}
+
+	return append(sources, embeddedTemplateSource())
+}
+
+// componentTemplateSource serves the templates of the component called name.
+// Only the files directory is served, so a component cannot replace the
+// manifest, the schema or the hooks.
+func componentTemplateSource(name string, fsys fs.FS) templateSource {
+	source := fsTemplateSource("component "+name, "", fsys)
+	read, list := source.read, source.list
+
+	source.read = func(name string) ([]byte, error) {
+		if !strings.HasPrefix(name, filesDir+"/") {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+
+		return read(name)
+	}
+
+	source.list = func() ([]string, error) {
+		names, err := list()
+		if err != nil {
+			return nil, err
+		}
+
+		files := names[:0]
+		for _, name := range names {
+			if strings.HasPrefix(name, filesDir+"/") {
+				files = append(files, name)
+			}
+		}
+
+		return files, nil
+	}
+
+	return source
+}
+
+// templatesFS holds the default templates.
+//
+//go:embed all:templates
+var templatesFS embed.FS
+
+// embeddedTemplateSource serves the templates directory embedded in the
+// binary.
+func embeddedTemplateSource() templateSource {
+	fsys, err := fs.Sub(templatesFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+
+	return fsTemplateSource(sourceEmbedded, "", fsys)
+}
+
+// dirTemplateSource serves the files below dir.
+func dirTemplateSource(label, dir string) templateSource {
+	return fsTemplateSource(label, dir, os.DirFS(dir))
+}
+
+// fsTemplateSource serves the files of fsys. dir is the directory fsys was
+// opened from, if any.
+func fsTemplateSource(label, dir string, fsys fs.FS) templateSource {
+	return templateSource{
+		label: label,
+		dir:   dir,
+		read: func(name string) ([]byte, error) {
+			return fs.ReadFile(fsys, name)
+		},
+		list: func() ([]string, error) {
+			var names []string
+
+			err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+
+				names = append(names, path)
+
+				return nil
+			})
+
+			return names, err
+		},
+	}
+}
+
+// lookup returns the content of the template called name and the source it was
+// found in. It returns an error wrapping fs.ErrNotExist if no source has it.
+func (ts *templateSet) lookup(name string) ([]byte, templateSource, error) {
+	content, index, err := ts.lookupFrom(name, 0)
+	if err != nil {
+		return nil, templateSource{}, err
+	}
+
+	return content, ts.sources[index], nil
+}
+
+// lookupFrom is like lookup, but only searches the sources from index start on
+// and returns the index of the source the template was found in.
+func (ts *templateSet) lookupFrom(name string, start int) ([]byte, int, error) {
+	if !fs.ValidPath(name) {
+		return nil, 0, fmt.Errorf("invalid template name %q", name)
+	}
+
+	for i := start; i < len(ts.sources); i++ {
+		source := ts.sources[i]
+
+		content, err := source.read(name)
+		if err == nil {
+			return content, i, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, i, fmt.Errorf("failed to read template %s from %s: %w", name, source.label, err)
+		}
+	}
+
+	return nil, 0, fmt.Errorf("template %s: %w", name, fs.ErrNotExist)
+}
+
+// exists reports whether any source has a template for the project file at
+// key. Templates that fail to load still exist; the error is reported when
+// they are rendered.
+func (ts *templateSet) exists(key string) bool {
+	_, err := ts.resolve(key)
+
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// names returns the names of every template available from any source, sorted.
+func (ts *templateSet) names() ([]string, error) {
+	seen := make(map[string]bool)
+
+	for _, source := range ts.sources {
+		names, err := source.list()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates from %s: %w", source.label, err)
+		}
+
+		for _, name := range names {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// filesDir is the directory of a template source holding the file templates,
+// addressed by the path of the file they generate.
+const filesDir = "files"
+
+// templateSuffix is an optional suffix of file template names. It keeps
+// templates of Go files, dotfiles and the like from being picked up by tools
+// working on the template directory; the generated file never has it.
+const templateSuffix = ".tmpl"
+
+// resolvedTemplate is the file template found for a project path, split into
+// its front matter and body.
+type resolvedTemplate struct {
+	name   string
+	header templateHeader
+	body   []byte
+	source templateSource
+	// index is the position of source in the template set.
+	index int
+}
+
+// resolve finds the template for the project file at key, a slash-separated
+// path relative to the project root. Each source is searched in priority order
+// for files/<key>.tmpl and then files/<key>. It returns an error wrapping
+// fs.ErrNotExist if neither exists.
+func (ts *templateSet) resolve(key string) (*resolvedTemplate, error) {
+	return ts.resolveFrom(key, 0)
+}
+
+// resolveFrom is like resolve, but only searches the sources from index start
+// on.
+func (ts *templateSet) resolveFrom(key string, start int) (*resolvedTemplate, error) {
+	if !fs.ValidPath(key) {
+		return nil, fmt.Errorf("invalid template path %q", key)
+	}
+
+	candidates := []string{path.Join(filesDir, key) + templateSuffix, path.Join(filesDir, key)}
+
+	for i := start; i < len(ts.sources); i++ {
+		source := ts.sources[i]
+
+		for _, name := range candidates {
+			content, err := source.read(name)
+			if err == nil {
+				header, body, err := splitFrontMatter(fmt.Sprintf("%s (%s)", name, source.label), content)
+				if err != nil {
+					return nil, err
+				}
+
+				return &resolvedTemplate{name: name, header: header, body: body, source: source, index: i}, nil
+			}
+
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read template %s from %s: %w", name, source.label, err)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no template for %s (looked for %s and %s): %w", key, candidates[0], candidates[1], fs.ErrNotExist)
+}
+
+// renderedFile is a file template rendered for a project.
+type renderedFile struct {
+	content []byte
+	mode    fs.FileMode
+}
+
+// render executes the template for the project file at key with data and the
+// helpers in templateFuncs, then applies the post-processors of its front
+// matter. Every template is rendered, whatever its name, unless its front
+// matter sets raw; literal "{{" must be written as {{ "{{" }}. It returns nil
+// if the front matter's when condition does not hold for data.
+//
+// A template whose front matter sets merge is added after (append) or before
+// (prepend) the file rendered from the sources below its own, and its
+// post-processors apply to the combined file. If its condition does not hold,
+// the file is rendered from those sources alone, and if theirs does not, it is
+// not generated.
+func (ts *templateSet) render(key string, data templateData) (*renderedFile, error) {
+	return ts.renderFrom(key, data, 0)
+}
+
+func (ts *templateSet) renderFrom(key string, data templateData, start int) (*renderedFile, error) {
+	resolved, err := ts.resolveFrom(key, start)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := resolved.header.holds(data)
+	if err != nil {
+		return nil, fmt.Errorf("template %s (%s): %v", resolved.name, resolved.source.label, err)
+	}
+
+	if !ok {
+		if resolved.header.Merge != "" {
+			return ts.renderFrom(key, data, resolved.index+1)
+		}
+
+		return nil, nil
+	}
+
+	content, mode := bytes.Clone(resolved.body), resolved.header.mode
+
+	if !resolved.header.Raw {
+		tmpl, err := template.New(resolved.name).Funcs(templateFuncs).Parse(string(resolved.body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s (%s): %v", resolved.name, resolved.source.label, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to execute template for %s (%s): %v", resolved.name, resolved.source.label, err)
+		}
+
+		content = buf.Bytes()
+	}
+
+	if resolved.header.Merge != "" {
+		base, err := ts.renderFrom(key, data, resolved.index+1)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("template %s (%s): nothing to %s to: %v", resolved.name, resolved.source.label, resolved.header.Merge, err)
+		} else if err != nil {
+			return nil, err
+		}
+
+		// the file is not generated at all
+		if base == nil {
+			return nil, nil
+		}
+
+		content = mergeContent(base.content, content, resolved.header.Merge)
+
+		if resolved.header.Mode == "" {
+			mode = base.mode
+		}
+	}
+
+	content, err = resolved.header.postProcess(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post-process %s (%s): %v", resolved.name, resolved.source.label, err)
+	}
+
+	return &renderedFile{content: content, mode: mode}, nil
+}
+
+// mergeContent adds part after (append) or before (prepend) base, on a line of
+// its own.
+func mergeContent(base, part []byte, merge string) []byte {
+	first, second := base, part
+	if merge == mergePrepend {
+		first, second = part, base
+	}
+
+	merged := append([]byte{}, first...)
+	if len(merged) > 0 && len(second) > 0 && !bytes.HasSuffix(merged, []byte("\n")) {
+		merged = append(merged, '\n')
+	}
+
+	return append(merged, second...)
+}
+
+// version identifies the templates the set resolves to. It is a short digest of
+// the name and resolved content of every template, so it changes whenever any
+// template, or the source it resolves to, does.
+func (ts *templateSet) version() string {
+	hash := sha256.New()
+
+	names, _ := ts.names()
+	for _, name := range names {
+		content, _, _ := ts.lookup(name)
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, content)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Template is a template known to ignite and the source it resolves to.
+type Template struct {
+	Name string
+	// Source is the label of the source: --templates, user config, embedded
+	// or the pack it comes from.
+	Source string
+	// Path is the file the template is read from, empty for the embedded
+	// templates.
+	Path string
+}
+
+// Templates returns every template, sorted by name, with the source it
+// resolves to when the templates are the directory or pack templates (see
+// Options.Templates).
+func Templates(templates string) ([]Template, error) {
+	ts, err := newTemplateSet(templates)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := ts.names()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Template, 0, len(names))
+
+	for _, name := range names {
+		_, source, err := ts.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		t := Template{Name: name, Source: source.label}
+		if source.dir != "" {
+			t.Path = filepath.Join(source.dir, filepath.FromSlash(name))
+		}
+
+		list = append(list, t)
+	}
+
+	return list, nil
+}
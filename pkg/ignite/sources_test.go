@@ -0,0 +1,74 @@
+package ignite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateDirectoryRecorded(t *testing.T) {
+	templates := t.TempDir()
+	readme := filepath.Join(templates, "files", "README.md.tmpl")
+
+	writeStaged(t, templates, map[string]string{"files/README.md.tmpl": "company readme\n"})
+
+	dir := writePlannedProject(t, Options{Module: "api", Database: "postgres", Controller: "http", Templates: templates})
+
+	content, _ := os.ReadFile(filepath.Join(dir, "README.md"))
+	if string(content) != "company readme\n" {
+		t.Fatalf("README.md = %q, want the template directory's", content)
+	}
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock.Templates == nil || lock.Templates.Source != templates || lock.Templates.Sum == "" {
+		t.Fatalf("templates recorded = %+v, want %s and its checksum", lock.Templates, templates)
+	}
+
+	report, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	if !report.Clean {
+		t.Errorf("project checked against other templates: %v", report.Issues)
+	}
+
+	if err := os.WriteFile(readme, []byte("company readme, revised\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Check(dir, CheckOptions{}); err == nil {
+		t.Error("Check used a template directory whose content changed")
+	}
+
+	if _, err := Check(dir, CheckOptions{Templates: templates}); err != nil {
+		t.Errorf("Check with --templates: %v", err)
+	}
+
+	upgrade, err := Upgrade(context.Background(), dir, UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	if got := upgradeStatus(upgrade, "README.md"); got != UpgradeChanged {
+		t.Errorf("README.md status = %q, want %q", got, UpgradeChanged)
+	}
+
+	content, _ = os.ReadFile(filepath.Join(dir, "README.md"))
+	if string(content) != "company readme, revised\n" {
+		t.Errorf("README.md = %q after upgrade, want the revised template", content)
+	}
+
+	if err := os.RemoveAll(templates); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Upgrade(context.Background(), dir, UpgradeOptions{}); err == nil {
+		t.Error("Upgrade fell back to other templates when the recorded directory is gone")
+	}
+}
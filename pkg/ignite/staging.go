@@ -0,0 +1,314 @@
+package ignite
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// stagingArea is a temporary directory in which a project is generated before
+// being moved into its target directory. Nothing is written to the target until
+// commit is called, so a failed or interrupted run can be undone by discarding
+// the staging area.
+type stagingArea struct {
+	// dir holds the project being generated. It is created inside tmp with
+	// the usual permissions rather than the private ones of a temporary
+	// directory, since it becomes the project directory on commit.
+	dir    string
+	tmp    string
+	target string
+	// targetExisted records whether target was already present, in which case
+	// the staged files are moved into it one by one instead of renaming dir.
+	targetExisted bool
+	// createdRoot is the topmost ancestor of target created by
+	// newStagingArea, removed again on discard.
+	createdRoot string
+	// fsys is the file system the staged project is copied into on commit,
+	// for targets other than a directory on disk.
+	fsys FS
+}
+
+// newStagingAreaFor creates a staging area for target. Directories on disk are
+// staged next to or inside them (see newStagingArea). Other file systems, e.g.
+// a MemFS or an ArchiveFS, are staged in a temporary directory, since hooks,
+// go and git run in a directory on disk, and its content is copied into them
+// on commit.
+func newStagingAreaFor(target FS) (*stagingArea, error) {
+	if d, ok := target.(*dirFS); ok {
+		return newStagingArea(d.dir)
+	}
+
+	s := &stagingArea{fsys: target}
+	if err := s.makeDir(os.TempDir(), "ignite-staging-*"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// newStagingArea creates a staging directory for target.
+//
+// If target does not exist yet the staging directory is created next to it, so
+// that commit can move the whole project into place with a single rename. If it
+// does exist the staging directory is created inside it. Missing parent
+// directories of target are created and removed again if the staging area is
+// discarded.
+func newStagingArea(target string) (*stagingArea, error) {
+	s := &stagingArea{target: target}
+
+	info, err := os.Stat(target)
+
+	switch {
+	case err == nil && !info.IsDir():
+		return nil, fmt.Errorf("%s exists and is not a directory", target)
+	case err == nil:
+		s.targetExisted = true
+	case errors.Is(err, os.ErrNotExist):
+		parent := filepath.Dir(target)
+
+		s.createdRoot = missingAncestor(parent)
+		if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", parent, err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to inspect %s: %w", target, err)
+	}
+
+	stagingParent := filepath.Dir(target)
+	if s.targetExisted {
+		stagingParent = target
+	}
+
+	if err := s.makeDir(stagingParent, ".ignite-staging-*"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// makeDir creates the temporary directory of the staging area in parent, named
+// after pattern as with os.MkdirTemp, and the project directory inside it.
+func (s *stagingArea) makeDir(parent, pattern string) error {
+	tmp, err := os.MkdirTemp(parent, pattern)
+	if err != nil {
+		s.discard()
+
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	s.tmp, s.dir = tmp, filepath.Join(tmp, "project")
+
+	if err := os.Mkdir(s.dir, os.ModePerm); err != nil {
+		s.discard()
+
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	log.Println("Staging project in", s.dir)
+
+	return nil
+}
+
+// discard removes the staging directory and any parent directories created for
+// it. It is safe to call more than once.
+func (s *stagingArea) discard() {
+	if s.tmp != "" {
+		log.Println("Removing staging directory", s.tmp)
+
+		if err := os.RemoveAll(s.tmp); err != nil {
+			log.Printf("failed to remove staging directory %s: %v", s.tmp, err)
+		}
+
+		s.tmp, s.dir = "", ""
+	}
+
+	if s.createdRoot != "" {
+		if err := os.RemoveAll(s.createdRoot); err != nil {
+			log.Printf("failed to remove directory %s: %v", s.createdRoot, err)
+		}
+
+		s.createdRoot = ""
+	}
+}
+
+// commit moves the staged project into the target directory.
+//
+// When the target did not exist, the staging directory is renamed to it, which
+// is atomic. Otherwise every staged file is moved into the existing target and,
+// should one of the moves fail, every move done so far is undone: new files and
+// directories are removed and overwritten files are restored from a backup.
+//
+// Staging areas of other file systems are copied into them, which cannot be
+// undone. Archives get no .git directory unless ArchiveFS.IncludeGit is set.
+func (s *stagingArea) commit() error {
+	if s.fsys != nil {
+		if err := copyTree(s.dir, s.fsys, includesGit(s.fsys)); err != nil {
+			return fmt.Errorf("failed to write project into %s: %w", fsName(s.fsys), err)
+		}
+
+		return nil
+	}
+
+	if !s.targetExisted {
+		if err := os.Rename(s.dir, s.target); err != nil {
+			return fmt.Errorf("failed to move project into %s: %w", s.target, err)
+		}
+
+		s.createdRoot = ""
+
+		return nil
+	}
+
+	backupDir, err := os.MkdirTemp(s.target, ".ignite-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer os.RemoveAll(backupDir)
+
+	var undo []func() error
+
+	err = filepath.WalkDir(s.dir, func(staged string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.dir, staged)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		dest := filepath.Join(s.target, rel)
+
+		if d.IsDir() {
+			if _, err := os.Stat(dest); err == nil {
+				return nil
+			}
+
+			if err := os.Mkdir(dest, os.ModePerm); err != nil {
+				return err
+			}
+
+			undo = append(undo, func() error { return os.Remove(dest) })
+
+			return nil
+		}
+
+		if _, err := os.Lstat(dest); err == nil {
+			backup := filepath.Join(backupDir, rel)
+
+			if err := os.MkdirAll(filepath.Dir(backup), os.ModePerm); err != nil {
+				return err
+			}
+
+			if err := os.Rename(dest, backup); err != nil {
+				return err
+			}
+
+			undo = append(undo, func() error { return os.Rename(backup, dest) })
+		}
+
+		if err := os.Rename(staged, dest); err != nil {
+			return err
+		}
+
+		undo = append(undo, func() error { return os.Remove(dest) })
+
+		return nil
+	})
+	if err != nil {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				log.Printf("rollback step failed: %v", undoErr)
+			}
+		}
+
+		return fmt.Errorf("failed to move project into %s: %w", s.target, err)
+	}
+
+	return nil
+}
+
+// includesGit reports whether the project's .git directory is copied into
+// fsys: always, except into archives without ArchiveFS.IncludeGit.
+func includesGit(fsys FS) bool {
+	for {
+		switch f := fsys.(type) {
+		case *subFS:
+			fsys = f.fsys
+		case *ArchiveFS:
+			return f.IncludeGit
+		default:
+			return true
+		}
+	}
+}
+
+// copyTree writes the directories and regular files below dir to fsys, with
+// their permissions. The .git directory at the root of dir is left out unless
+// withGit is true.
+func copyTree(dir string, fsys FS, withGit bool) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+
+		if name == ".git" && !withGit {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return fsys.MkdirAll(name, info.Mode().Perm())
+		}
+
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		return fsys.WriteFile(name, content, info.Mode().Perm())
+	})
+}
+
+// missingAncestor returns the topmost directory of path that does not exist yet,
+// or an empty string if path already exists.
+func missingAncestor(path string) string {
+	missing := ""
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return missing
+		}
+
+		missing = path
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return missing
+		}
+
+		path = parent
+	}
+}
@@ -0,0 +1,170 @@
+package ignite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeStaged(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStagingCommitNewTarget(t *testing.T) {
+	parent := t.TempDir()
+	target := filepath.Join(parent, "nested", "api")
+
+	s, err := newStagingArea(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.discard()
+
+	writeStaged(t, s.dir, map[string]string{"cmd/main.go": "package main\n"})
+
+	if err := s.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.discard()
+
+	content, err := os.ReadFile(filepath.Join(target, "cmd", "main.go"))
+	if err != nil || string(content) != "package main\n" {
+		t.Fatalf("committed file = %q, %v", content, err)
+	}
+
+	// the project directory gets the permissions of any new directory, not
+	// the private ones of a temporary directory
+	reference := filepath.Join(parent, "reference")
+	if err := os.Mkdir(reference, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := os.Stat(reference)
+	got, _ := os.Stat(target)
+
+	if got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("project directory mode = %v, want %v", got.Mode().Perm(), want.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("%d entries next to the project, want only the project", len(entries))
+	}
+}
+
+func TestStagingDiscardNewTarget(t *testing.T) {
+	parent := t.TempDir()
+
+	s, err := newStagingArea(filepath.Join(parent, "nested", "deeper", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeStaged(t, s.dir, map[string]string{"README.md": "# api\n"})
+
+	s.discard()
+	s.discard()
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("discard left %d entries, including the created parents", len(entries))
+	}
+}
+
+func TestStagingCommitRollback(t *testing.T) {
+	target := t.TempDir()
+
+	writeStaged(t, target, map[string]string{
+		"README.md": "my notes\n",
+		// a file where the staged project has a directory
+		"a": "not a directory\n",
+	})
+
+	s, err := newStagingArea(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.discard()
+
+	// files are moved in lexical order: README.md is overwritten before
+	// moving a/x fails
+	writeStaged(t, s.dir, map[string]string{
+		"README.md": "# api\n",
+		"a/x":       "x\n",
+		"new.txt":   "new\n",
+	})
+
+	if err := s.commit(); err == nil {
+		t.Fatal("commit succeeded, want an error")
+	}
+
+	s.discard()
+
+	content, err := os.ReadFile(filepath.Join(target, "README.md"))
+	if err != nil || string(content) != "my notes\n" {
+		t.Errorf("README.md = %q, %v after rollback, want the original content", content, err)
+	}
+
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	if len(names) != 2 {
+		t.Errorf("target holds %v after rollback, want only README.md and a", names)
+	}
+}
+
+func TestStagingCommitExistingTarget(t *testing.T) {
+	target := t.TempDir()
+
+	writeStaged(t, target, map[string]string{"notes.txt": "keep\n"})
+
+	s, err := newStagingArea(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.discard()
+
+	writeStaged(t, s.dir, map[string]string{"cmd/main.go": "package main\n"})
+
+	if err := s.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.discard()
+
+	for name, want := range map[string]string{"notes.txt": "keep\n", "cmd/main.go": "package main\n"} {
+		content, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil || string(content) != want {
+			t.Errorf("%s = %q, %v, want %q", name, content, err, want)
+		}
+	}
+
+	entries, _ := os.ReadDir(target)
+	if len(entries) != 2 {
+		t.Errorf("target holds %d entries, want the staging directory removed", len(entries))
+	}
+}
@@ -0,0 +1,237 @@
+package ignite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Outcomes of upgrading a single file.
+const (
+	UpgradeAdded      = "added"
+	UpgradeChanged    = "changed"
+	UpgradeConflicted = "conflicted"
+	UpgradeUntouched  = "untouched"
+	UpgradeSkipped    = "skipped"
+)
+
+// UpgradeReport is the outcome of Upgrade.
+type UpgradeReport struct {
+	Project string
+	// FromVersion and ToVersion are the template versions the project was
+	// generated from and upgraded to.
+	FromVersion string
+	ToVersion   string
+	Files       []UpgradeFile
+}
+
+// UpgradeFile is the outcome of upgrading a file.
+type UpgradeFile struct {
+	Path string
+	// Status is one of the Upgrade constants.
+	Status string
+	// Reason explains why a file was skipped.
+	Reason string
+}
+
+// UpgradeOptions configures Upgrade.
+type UpgradeOptions struct {
+	// Templates, if not empty, replaces the template pack recorded in the
+	// project's lock file, which is otherwise used at its recorded ref (see
+	// Options.Templates).
+	Templates string
+	// DryRun only reports what would change, without writing anything.
+	DryRun bool
+}
+
+// Upgrade merges the latest templates into the ignite project in dir and
+// reports the outcome for every file. Regions changed both in the project and
+// in the templates are written with conflict markers.
+func Upgrade(ctx context.Context, dir string, opts UpgradeOptions) (*UpgradeReport, error) {
+	ts, err := projectTemplateSet(opts.Templates, dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return runUpgrade(ctx, dir, ts, opts.DryRun)
+}
+
+// runUpgrade merges the templates in ts into the ignite project in dir.
+//
+// For every file the templates produce for the project's recorded options it
+// does the following:
+//
+//   - if the file is new to the templates, it is added.
+//   - if the user deleted it or it still has unresolved conflict markers, it
+//     is skipped.
+//   - otherwise the file is merged three ways with mergeThreeWay. The base is
+//     the snapshot in .ignite/base or, for projects without one, the current
+//     file if its checksum still matches the lock file.
+//
+// Merged files, new directories, the refreshed base snapshots and the updated
+// lock file are written through a staging area unless dryRun is set.
+func runUpgrade(ctx context.Context, dir string, ts *templateSet, dryRun bool) (*UpgradeReport, error) {
+	lock, err := readLockFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	p := lock.initializer()
+	p.templates = ts
+
+	next, err := p.buildPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan project structure: %w", err)
+	}
+
+	report := &UpgradeReport{
+		Project:     lock.Project.Name,
+		FromVersion: lock.TemplateVersion,
+		ToVersion:   p.templates.version(),
+	}
+
+	plan := &projectPlan{}
+	rendered := &projectPlan{}
+	// locked holds the files whose checksums are recorded in the lock file:
+	// the written ones, except that conflicted files are recorded with their
+	// rendered content, so that check reports them until they are resolved
+	locked := &projectPlan{}
+
+	for _, item := range next.items {
+		fullPath := filepath.Join(dir, filepath.FromSlash(item.path))
+
+		if item.isDir {
+			if _, err := os.Stat(fullPath); errors.Is(err, os.ErrNotExist) {
+				plan.items = append(plan.items, item)
+				report.Files = append(report.Files, UpgradeFile{Path: item.path + "/", Status: UpgradeAdded})
+			}
+
+			continue
+		}
+
+		file, merged, err := upgradeFileContent(dir, item, lock.Files[item.path])
+		if err != nil {
+			return nil, err
+		}
+
+		report.Files = append(report.Files, file)
+
+		if file.Status == UpgradeSkipped {
+			continue
+		}
+
+		rendered.items = append(rendered.items, item)
+
+		if file.Status == UpgradeUntouched {
+			continue
+		}
+
+		plan.items = append(plan.items, planItem{path: item.path, content: merged, mode: item.mode})
+
+		if file.Status == UpgradeConflicted {
+			locked.items = append(locked.items, item)
+		} else {
+			locked.items = append(locked.items, plan.items[len(plan.items)-1])
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	lock.recordTemplates(p.templates)
+	lock.record(p, locked)
+
+	plan, err = appendMetadata(plan, lock, rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	stage, err := newStagingArea(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer stage.discard()
+
+	if err := createDirectories(plan, DirFS(stage.dir)); err != nil {
+		return nil, fmt.Errorf("failed to upgrade project: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("upgrade interrupted: %w", err)
+	}
+
+	if err := stage.commit(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// upgradeFileContent decides how the planned file item is upgraded in the
+// project in dir, given the checksum recorded for it in the lock file, and
+// returns the content to write for added, changed and conflicted files.
+func upgradeFileContent(dir string, item planItem, recorded string) (UpgradeFile, []byte, error) {
+	file := UpgradeFile{Path: item.path}
+
+	current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(item.path)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return file, nil, fmt.Errorf("failed to read %s: %w", item.path, err)
+	}
+
+	base, baseErr := readBase(dir, item.path)
+	if baseErr != nil {
+		return file, nil, fmt.Errorf("failed to read base snapshot of %s: %w", item.path, baseErr)
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		if recorded == "" && base == nil {
+			file.Status = UpgradeAdded
+
+			return file, item.content, nil
+		}
+
+		file.Status, file.Reason = UpgradeSkipped, "deleted in the project"
+
+		return file, nil, nil
+	}
+
+	if hasConflictMarkers(current) {
+		file.Status, file.Reason = UpgradeSkipped, "has unresolved conflict markers"
+
+		return file, nil, nil
+	}
+
+	if base == nil && recorded == checksum(current) {
+		base = current
+	}
+
+	merged, conflicted := mergeThreeWay(base, current, item.content)
+
+	switch {
+	case bytes.Equal(merged, current):
+		file.Status = UpgradeUntouched
+	case conflicted:
+		file.Status = UpgradeConflicted
+	default:
+		file.Status = UpgradeChanged
+	}
+
+	return file, merged, nil
+}
+
+// Count returns the number of files with the given status.
+func (r *UpgradeReport) Count(status string) int {
+	n := 0
+
+	for _, f := range r.Files {
+		if f.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,173 @@
+package ignite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ageFile makes the file at rel in the project in dir look generated by older
+// templates that had an extra second line, which the current templates
+// removed, and writes current, given the old content, as the project file.
+func ageFile(t *testing.T, dir, rel string, current func(old string) string) string {
+	t.Helper()
+
+	rendered, err := os.ReadFile(filepath.Join(dir, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, rest, _ := strings.Cut(string(rendered), "\n")
+	old := first + "\n// removed by the new templates\n" + rest
+
+	writeStaged(t, dir, map[string]string{
+		rel:                 current(old),
+		baseDir + "/" + rel: old,
+	})
+
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Files[rel] = checksum([]byte(old))
+	writeLockFile(t, dir, lock)
+
+	return string(rendered)
+}
+
+func writeLockFile(t *testing.T, dir string, lock *lockFile) {
+	t.Helper()
+
+	plan, err := appendMetadata(&projectPlan{}, lock, &projectPlan{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeStaged(t, dir, map[string]string{lockFileName: string(plan.items[0].content)})
+}
+
+func upgradeStatus(report *UpgradeReport, rel string) string {
+	for _, f := range report.Files {
+		if f.Path == rel {
+			return f.Status
+		}
+	}
+
+	return ""
+}
+
+func TestUpgradeMerge(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	rendered := ageFile(t, dir, "pkg/errors.go", func(old string) string { return old + "// mine\n" })
+
+	report, err := Upgrade(context.Background(), dir, UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	if got := upgradeStatus(report, "pkg/errors.go"); got != UpgradeChanged {
+		t.Errorf("status = %q, want %q", got, UpgradeChanged)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "pkg", "errors.go"))
+	if want := rendered + "// mine\n"; string(content) != want {
+		t.Errorf("merged file = %q, want %q", content, want)
+	}
+
+	base, _ := readBase(dir, "pkg/errors.go")
+	if string(base) != rendered {
+		t.Error("base snapshot not refreshed to the new templates")
+	}
+}
+
+func TestUpgradeConflict(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	rendered := ageFile(t, dir, "pkg/errors.go", func(old string) string {
+		return strings.Replace(old, "// removed by the new templates", "// kept by me", 1)
+	})
+
+	report, err := Upgrade(context.Background(), dir, UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	if got := upgradeStatus(report, "pkg/errors.go"); got != UpgradeConflicted {
+		t.Fatalf("status = %q, want %q", got, UpgradeConflicted)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "pkg", "errors.go"))
+	if !hasConflictMarkers(content) {
+		t.Errorf("conflicted file has no markers: %q", content)
+	}
+
+	// the conflicted file is recorded as the template has it, so check
+	// reports it until it is resolved
+	lock, err := readLockFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock.Files["pkg/errors.go"] != checksum([]byte(rendered)) {
+		t.Error("conflicted file recorded with its conflict markers")
+	}
+
+	check, err := Check(dir, CheckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if check.Clean {
+		t.Error("check reports a project with conflict markers clean")
+	}
+}
+
+func TestUpgradeDryRun(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	ageFile(t, dir, "pkg/errors.go", func(old string) string { return old })
+
+	before, _ := os.ReadFile(filepath.Join(dir, "pkg", "errors.go"))
+
+	report, err := Upgrade(context.Background(), dir, UpgradeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	if got := upgradeStatus(report, "pkg/errors.go"); got != UpgradeChanged {
+		t.Errorf("status = %q, want %q", got, UpgradeChanged)
+	}
+
+	after, _ := os.ReadFile(filepath.Join(dir, "pkg", "errors.go"))
+	if string(after) != string(before) {
+		t.Error("dry run changed the project")
+	}
+}
+
+func TestAddKeepsOtherBaseSnapshots(t *testing.T) {
+	dir := writePlannedProject(t, Options{Module: "github.com/acme/api", Database: "postgres", Controller: "http"})
+
+	rendered := ageFile(t, dir, "pkg/errors.go", func(old string) string { return old })
+
+	if _, err := Add(context.Background(), dir, AddOptions{Component: "dockerfile"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	base, _ := readBase(dir, "pkg/errors.go")
+	if string(base) == rendered {
+		t.Fatal("add replaced the base snapshot of a file it did not touch")
+	}
+
+	if _, err := Upgrade(context.Background(), dir, UpgradeOptions{}); err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "pkg", "errors.go"))
+	if string(content) != rendered {
+		t.Errorf("upgrade after add did not apply the template change: %q", content)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/EmilioCliff/ignite/pkg/ignite"
 	"github.com/manifoldco/promptui"
 )
 
@@ -12,51 +14,118 @@ type PromptContent struct {
 	success  string
 }
 
-// promptGetInput will prompt the user for input using the Label field of the PromptContent object
-// and return the user's response as a string. If the user enters an empty string, an error will
-// be returned.
-func (pc *PromptContent) promptGetInput() string {
+// promptValidatedInput prompts the user for input using the Label field of the
+// PromptContent object, offering def as the default answer. Answers are
+// checked with validate as they are typed and only a valid one is returned.
+func (pc *PromptContent) promptValidatedInput(def string, validate func(string) error) (string, error) {
 	prompt := promptui.Prompt{
-		Label: pc.label,
+		Label:    pc.label,
+		Default:  def,
+		Validate: validate,
 	}
 
 	result, err := prompt.Run()
 	if err != nil {
-		fmt.Printf("Prompt failed %v\n", err)
+		return "", fmt.Errorf("prompt failed: %w", err)
 	}
 
-	return result
+	return result, nil
 }
 
-// promptSelect will prompt the user to select one of the given items from a list. The Label
-// field of the PromptContent object will be used as the prompt label. The user will be
-// repeatedly prompted until they select a valid item. The selected item will be returned as
-// a string.
-func (pc *PromptContent) promptSelect(items []string) string {
-	index := -1
+// promptConfirm asks the yes/no question in the Label field of the
+// PromptContent object and reports whether the user answered yes.
+func (pc *PromptContent) promptConfirm() bool {
+	prompt := promptui.Prompt{
+		Label:     pc.label,
+		IsConfirm: true,
+	}
 
-	var result string
+	_, err := prompt.Run()
 
-	var err error
+	return err == nil
+}
 
+// promptSelect will prompt the user to select one of the given items from a list. The Label
+// field of the PromptContent object will be used as the prompt label. The selected item will be
+// returned as a string, or an error if the prompt is interrupted (Ctrl-C) or the input ends.
+func (pc *PromptContent) promptSelect(items []string) (string, error) {
 	templates := &promptui.SelectTemplates{
 		Active:   "{{ . | green }}",
 		Inactive: "{{ . | red }}",
-		Selected: fmt.Sprintf("✔ {{ . | bold | green }}"),
+		Selected: "✔ {{ . | bold | green }}",
+	}
+
+	prompt := promptui.Select{
+		Label:     pc.label,
+		Items:     items,
+		Templates: templates,
+	}
+
+	_, result, err := prompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("prompt failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// promptVariable asks for the value of v, with a selection for variables with
+// options and a validated input otherwise.
+func promptVariable(v *ignite.Variable, _ map[string]any) (string, error) {
+	label := v.Prompt
+	if label == "" {
+		label = v.Name
 	}
 
-	for index < 0 {
-		prompt := promptui.Select{
-			Label:     pc.label,
-			Items:     items,
-			Templates: templates,
+	prompt := PromptContent{
+		label:    label,
+		errorMsg: "please provide a value for " + v.Name,
+		success:  v.Name + ": ",
+	}
+
+	switch {
+	case len(v.Options) > 0:
+		return prompt.promptSelect(v.Options)
+	case v.Type == "bool":
+		prompt.label += " (yes/no)"
+	}
+
+	return prompt.promptValidatedInput(v.Default, func(answer string) error {
+		if answer == "" {
+			if v.Required {
+				return fmt.Errorf("%s", prompt.errorMsg)
+			}
+
+			return nil
+		}
+
+		_, err := v.Parse(answer)
+
+		return err
+	})
+}
+
+// approveHooks prints the hooks of the template pack and asks the user to
+// approve them.
+func approveHooks(hooks []ignite.Hook) (bool, error) {
+	fmt.Printf("The template pack runs %d hook(s) while generating the project:\n\n", len(hooks))
+
+	for _, hook := range hooks {
+		commands := "none"
+		if len(hook.Commands) > 0 {
+			commands = strings.Join(hook.Commands, ", ")
 		}
 
-		index, result, err = prompt.Run()
-		if err != nil {
-			return fmt.Sprintf("Prompt failed %v\n", err)
+		fmt.Printf("%s (%s, %s), may run: %s\n", hook.Script, hook.Source, hook.Phase, commands)
+
+		for _, line := range strings.Split(strings.TrimRight(hook.Content, "\n"), "\n") {
+			fmt.Println("  | " + line)
 		}
+
+		fmt.Println()
 	}
 
-	return result
+	prompt := PromptContent{label: "Run these hooks"}
+
+	return prompt.promptConfirm(), nil
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/EmilioCliff/ignite/pkg/ignite"
+	"github.com/spf13/cobra"
+)
+
+func newTemplatesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "templates",
+		Aliases: []string{"template"},
+		Short:   "Inspect the templates ignite uses and create template packs",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List every template and the source it resolves to",
+		Long: `list shows every template known to ignite and where it is read from.
+
+Templates are looked up, in order, in the --templates directory, in the
+ignite/templates directory of the user's configuration directory and in the
+templates embedded in the binary. The first source that has a template wins.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			templates, err := ignite.Templates(templatesFromFlags(cmd))
+			if err == nil {
+				err = printTemplates(os.Stdout, templates)
+			}
+
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	})
+
+	cmd.AddCommand(newCaptureCmd())
+	cmd.AddCommand(newPackTestCmd())
+
+	return cmd
+}
+
+func printTemplates(w io.Writer, templates []ignite.Template) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEMPLATE\tSOURCE\tPATH")
+
+	for _, t := range templates {
+		location := "-"
+		if t.Path != "" {
+			location = t.Path
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.Source, location)
+	}
+
+	return tw.Flush()
+}
+
+func newCaptureCmd() *cobra.Command {
+	var (
+		out    string
+		module string
+		name   string
+		dbType string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "capture <project_path>",
+		Short: "Turn an existing project into a template pack",
+		Long: `capture walks a project and writes a template pack reproducing it.
+
+Every file not ignored by the project's .gitignore files becomes a template in
+<out>/files, and <out>/manifest.yaml lists them together with the empty
+directories. Occurrences of the module path, the project name and the database
+type, in file contents and paths, are replaced with {{ .ModulePath }},
+{{ .ProjectName }} and {{ .DBType }}. They are read from the project's
+.ignite.yaml or go.mod unless given with the flags below.
+
+.git, the ignite metadata, go.mod and go.sum are never captured: ignite
+creates the module with go mod init when it generates a project.
+
+Use the pack with: ignite <project_name> --templates <out>`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			n, err := ignite.Capture(args[0], out, ignite.CaptureOptions{
+				Module:   module,
+				Name:     name,
+				Database: strings.ToLower(dbType),
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Captured %d files from %s into %s\n", n, args[0], out)
+		},
+	}
+
+	cmd.Flags().StringVarP(&out, "out", "o", "", "Directory the template pack is written to (must not exist or be empty)")
+	cmd.Flags().StringVar(&module, "module", "", "Module path of the project (defaults to the one in .ignite.yaml or go.mod)")
+	cmd.Flags().StringVar(&name, "name", "", "Project name (defaults to the last element of the module path)")
+	cmd.Flags().StringVarP(&dbType, "database", "d", "", "Database type of the project (defaults to the one in .ignite.yaml)")
+	cmd.MarkFlagRequired("out")
+
+	return cmd
+}
+
+func newPackTestCmd() *cobra.Command {
+	var (
+		update bool
+		run    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test [pack_dir]",
+		Short: "Render a template pack for each test case and compare it with golden trees",
+		Long: `test checks the output of a template pack (defaults to the current directory).
+
+Each answer file tests/<case>.yaml of the pack gives the project and the
+template variables of a test case:
+
+  project: github.com/acme/billing
+  variables:
+    database: postgres
+    controller: http
+    workflow: yes
+
+The pack is rendered in memory for every case, and the files
+are compared with the golden tree tests/<case>/: missing, unexpected and
+modified files, and files whose executable bit differs, are reported, the
+modified ones as unified diffs. Directories are not compared, as git does not
+keep empty ones.
+
+Templates missing from the pack are taken from the embedded templates; the
+templates in the user's configuration directory are never used, so results do
+not depend on the machine. With --update (-u, or -update as for go test) the
+golden trees are rewritten from the rendered output instead. The command exits
+non-zero if any case fails.`,
+		// the flags are parsed in Run, so that the single-dash spelling of go
+		// test (-update, -run) works too
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Flags().Parse(goTestFlags(args)); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if help, _ := cmd.Flags().GetBool("help"); help {
+				cmd.Help()
+
+				return
+			}
+
+			args = cmd.Flags().Args()
+			if len(args) > 1 {
+				fmt.Printf("Error: accepts at most 1 arg(s), received %d\n", len(args))
+				os.Exit(1)
+			}
+
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+
+			results, err := ignite.TestPack(dir, ignite.PackTestOptions{Run: run, Update: update})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			failed := false
+
+			for _, result := range results {
+				printPackTestResult(os.Stdout, result)
+
+				if len(result.Problems) > 0 {
+					failed = true
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&update, "update", "u", false, "Rewrite the golden trees from the rendered output")
+	cmd.Flags().StringVar(&run, "run", "", "Only run the cases whose name matches this regular expression")
+
+	return cmd
+}
+
+// goTestFlags rewrites the single-dash -update and -run flags of go test in
+// args to the double-dash spelling of pflag.
+func goTestFlags(args []string) []string {
+	normalized := make([]string, 0, len(args))
+
+	for i, arg := range args {
+		if arg == "--" {
+			return append(normalized, args[i:]...)
+		}
+
+		name, _, _ := strings.Cut(strings.TrimPrefix(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && (name == "update" || name == "run") {
+			arg = "-" + arg
+		}
+
+		normalized = append(normalized, arg)
+	}
+
+	return normalized
+}
+
+func printPackTestResult(w io.Writer, r *ignite.PackTestResult) {
+	switch {
+	case r.Updated:
+		fmt.Fprintf(w, "updated  %s\n", r.Name)
+	case len(r.Problems) == 0:
+		fmt.Fprintf(w, "ok       %s\n", r.Name)
+	default:
+		fmt.Fprintf(w, "FAIL     %s\n", r.Name)
+
+		for _, problem := range r.Problems {
+			fmt.Fprintln(w, "    "+strings.ReplaceAll(strings.TrimSuffix(problem, "\n"), "\n", "\n    "))
+		}
+	}
+}
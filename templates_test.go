@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGoTestFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"./pack", "-update"}, want: []string{"./pack", "--update"}},
+		{args: []string{"-run", "http", "./pack"}, want: []string{"--run", "http", "./pack"}},
+		{args: []string{"-run=http"}, want: []string{"--run=http"}},
+		{args: []string{"--update", "-u", "-v"}, want: []string{"--update", "-u", "-v"}},
+		{args: []string{"--", "-update"}, want: []string{"--", "-update"}},
+	}
+
+	for _, tt := range tests {
+		if got := goTestFlags(tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("goTestFlags(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/EmilioCliff/ignite/pkg/ignite"
+	"github.com/spf13/cobra"
+)
+
+func newUpgradeCmd() *cobra.Command {
+	var (
+		path   string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Merge the latest templates into an existing ignite project",
+		Long: `upgrade re-renders the templates for the options recorded in the project's
+.ignite.yaml and merges them into the project.
+
+Every file is merged three ways between the content ignite originally generated
+(kept in .ignite/base), the current file and the new template. Changes made only
+by the user or only by the template are applied cleanly; regions changed by both
+are written with conflict markers. A report lists every changed, conflicted and
+untouched file, and the command exits non-zero if any conflict was written.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			setupConsoleLogging(verbose)
+
+			var err error
+
+			if path == "" {
+				path, err = MustGetPwd()
+				if err != nil {
+					log.Panic(err)
+				}
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			report, err := ignite.Upgrade(ctx, path, ignite.UpgradeOptions{
+				Templates: templatesFromFlags(cmd),
+				DryRun:    dryRun,
+			})
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			printUpgradeReport(os.Stdout, report, dryRun)
+
+			if report.Count(ignite.UpgradeConflicted) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&path, "path", "p", "", "Path of the project (defaults to current directory)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report what would change without writing anything")
+
+	return cmd
+}
+
+func printUpgradeReport(w io.Writer, r *ignite.UpgradeReport, dryRun bool) {
+	title := "Upgraded"
+	if dryRun {
+		title = "Upgrade plan for"
+	}
+
+	fmt.Fprintf(w, "%s %s (templates %s -> %s):\n", title, r.Project, r.FromVersion, r.ToVersion)
+
+	for _, f := range r.Files {
+		if f.Reason != "" {
+			fmt.Fprintf(w, "  %-11s %s (%s)\n", f.Status, f.Path, f.Reason)
+		} else {
+			fmt.Fprintf(w, "  %-11s %s\n", f.Status, f.Path)
+		}
+	}
+
+	fmt.Fprintf(w, "%d added, %d changed, %d conflicted, %d untouched, %d skipped\n",
+		r.Count(ignite.UpgradeAdded), r.Count(ignite.UpgradeChanged), r.Count(ignite.UpgradeConflicted),
+		r.Count(ignite.UpgradeUntouched), r.Count(ignite.UpgradeSkipped))
+
+	if r.Count(ignite.UpgradeConflicted) > 0 {
+		fmt.Fprintf(w, "Resolve the regions between %q and %q markers in the conflicted files.\n", ignite.ConflictMarkerCurrent, ignite.ConflictMarkerTemplate)
+	}
+}